@@ -0,0 +1,148 @@
+// Copyright (C) 2013-2018 by Maxim Bublis <b@codemonkey.ru>
+//
+// Permission is hereby granted, free of charge, to any person obtaining
+// a copy of this software and associated documentation files (the
+// "Software"), to deal in the Software without restriction, including
+// without limitation the rights to use, copy, modify, merge, publish,
+// distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so, subject to
+// the following conditions:
+//
+// The above copyright notice and this permission notice shall be
+// included in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+// EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF
+// MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE
+// LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+// OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION
+// WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package uuid
+
+import (
+	"context"
+	"io"
+	"time"
+)
+
+// BatchGenerator is implemented by generators that can fill a slice with
+// UUIDs faster than generating them one at a time.
+type BatchGenerator interface {
+	FillV4(dst []UUID) error
+	FillV6(dst []UUID) error
+	FillV7(dst []UUID) error
+	FillV7AtTime(dst []UUID, atTime time.Time) error
+}
+
+// interface check -- build will fail if *Gen doesn't satisfy BatchGenerator
+var _ BatchGenerator = (*Gen)(nil)
+
+// FillV4 fills dst with randomly generated UUIDs, reading the random data
+// for all of them at once. On error, dst is left unchanged.
+func (g *Gen) FillV4(dst []UUID) error {
+	if len(dst) == 0 {
+		return nil
+	}
+	buf := make([]byte, len(dst)*Size)
+	if _, err := io.ReadFull(g.rand, buf); err != nil {
+		return err
+	}
+	for i := range dst {
+		u := &dst[i]
+		copy(u[:], buf[i*Size:])
+		u.SetVersion(V4)
+		u.SetVariant(VariantRFC9562)
+	}
+	return nil
+}
+
+// FillV6 fills dst with V6 UUIDs based on the current timestamp, as if by
+// len(dst) calls to NewV6. The generator reserves consecutive timestamps for
+// the whole batch, so the UUIDs are strictly increasing. On error, dst is
+// left unchanged.
+func (g *Gen) FillV6(dst []UUID) error {
+	if g.stateless {
+		return g.child().FillV6(dst)
+	}
+	if len(dst) == 0 {
+		return nil
+	}
+
+	atTime := g.embeddedTime(g.epochFunc())
+	if err := checkTimeRange(V6, atTime); err != nil {
+		return err
+	}
+
+	timeNow, _, err := g.getClockSequence(context.Background(), V6, atTime, true, uint64(len(dst)))
+	if err != nil {
+		return err
+	}
+
+	buf := make([]byte, len(dst)*8)
+	if _, err = io.ReadFull(g.rand, buf); err != nil {
+		return err
+	}
+	for i := range dst {
+		u := &dst[i]
+		copy(u[8:], buf[i*8:])
+		encodeV6(u, timeNow+uint64(i))
+	}
+	return nil
+}
+
+// FillV7 fills dst with V7 UUIDs based on the current timestamp, as if by
+// len(dst) calls to NewV7. The generator reserves a contiguous block of
+// counter values for the whole batch, so the UUIDs are strictly increasing
+// whatever the MonotonicPolicy: MonotonicWrap borrows the following
+// milliseconds like MonotonicBorrow when the counter overflows within the
+// batch. On error, dst is left unchanged.
+func (g *Gen) FillV7(dst []UUID) error {
+	return g.fillV7(context.Background(), dst, g.epochFunc(), true)
+}
+
+// FillV7AtTime is like FillV7, but for the provided timestamp.
+func (g *Gen) FillV7AtTime(dst []UUID, atTime time.Time) error {
+	return g.fillV7(context.Background(), dst, atTime, false)
+}
+
+func (g *Gen) fillV7(ctx context.Context, dst []UUID, atTime time.Time, now bool) error {
+	if g.stateless {
+		return g.child().fillV7(ctx, dst, atTime, now)
+	}
+	if len(dst) == 0 {
+		return nil
+	}
+
+	atTime = g.embeddedTime(atTime)
+	if err := checkTimeRange(V7, atTime); err != nil {
+		return err
+	}
+
+	// One read provides rand_b for every UUID, followed by the random data
+	// for the counter, if any.
+	n := len(dst) * 8
+	if g.v7Reseeded() {
+		n += len(dst) * v7SeedSize
+	}
+	buf := make([]byte, n)
+	if _, err := io.ReadFull(g.rand, buf); err != nil {
+		return err
+	}
+	randB, rnd := buf[:len(dst)*8], buf[len(dst)*8:]
+	if len(rnd) == 0 {
+		rnd = nil
+	}
+
+	t := make([]v7Time, len(dst))
+	if err := g.getV7Time(ctx, atTime, now, rnd, t); err != nil {
+		return err
+	}
+	for i := range dst {
+		u := &dst[i]
+		copy(u[8:], randB[i*8:])
+		g.encodeV7(u, t[i])
+	}
+	return nil
+}
@@ -0,0 +1,257 @@
+// Copyright (C) 2013-2018 by Maxim Bublis <b@codemonkey.ru>
+//
+// Permission is hereby granted, free of charge, to any person obtaining
+// a copy of this software and associated documentation files (the
+// "Software"), to deal in the Software without restriction, including
+// without limitation the rights to use, copy, modify, merge, publish,
+// distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so, subject to
+// the following conditions:
+//
+// The above copyright notice and this permission notice shall be
+// included in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+// EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF
+// MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE
+// LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+// OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION
+// WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package uuid
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestFill(t *testing.T) {
+	t.Run("V4", testFillV4)
+	t.Run("V6", testFillV6)
+	t.Run("V7", testFillV7)
+	t.Run("V7Methods", testFillV7Methods)
+	t.Run("V7AtTimeOverflow", testFillV7AtTimeOverflow)
+	t.Run("V7Error", testFillV7Error)
+	t.Run("Empty", testFillEmpty)
+	t.Run("FaultyRand", testFillFaultyRand)
+	t.Run("Stateless", testFillStateless)
+}
+
+func testFillV4(t *testing.T) {
+	dst := make([]UUID, 100)
+	if err := NewGen().FillV4(dst); err != nil {
+		t.Fatal(err)
+	}
+	seen := make(map[UUID]bool, len(dst))
+	for _, u := range dst {
+		if got, want := u.Version(), V4; got != want {
+			t.Fatalf("%v: version = %d, want %d", u, got, want)
+		}
+		if got, want := u.Variant(), VariantRFC9562; got != want {
+			t.Fatalf("%v: variant = %d, want %d", u, got, want)
+		}
+		if seen[u] {
+			t.Fatalf("duplicate UUID %v", u)
+		}
+		seen[u] = true
+	}
+}
+
+func testFillV6(t *testing.T) {
+	now := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	g := NewGenWithOptions(WithEpochFunc(func() time.Time { return now }))
+
+	dst := make([]UUID, 100)
+	if err := g.FillV6(dst); err != nil {
+		t.Fatal(err)
+	}
+	next, err := g.NewV6()
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkIncreasing(t, append(dst, next), V6, 8)
+
+	ts, err := TimestampFromV6(dst[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := uint64(ts), g.getEpoch(now); got != want {
+		t.Errorf("first timestamp = %d, want %d", got, want)
+	}
+}
+
+func testFillV7(t *testing.T) {
+	now := time.UnixMilli(1645557742000)
+	g := NewGenWithOptions(WithEpochFunc(func() time.Time { return now }))
+
+	// more UUIDs than the 12-bit counter allows in a millisecond
+	dst := make([]UUID, 5000)
+	if err := g.FillV7(dst); err != nil {
+		t.Fatal(err)
+	}
+	next, err := g.NewV7()
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkIncreasing(t, append(dst, next), V7, 16)
+}
+
+func testFillV7Methods(t *testing.T) {
+	tests := []struct {
+		name string
+		opt  GenOption
+	}{
+		{"SubMillisecond", WithV7SubMillisecond()},
+		{"Counter", WithV7Counter(18)},
+		{"MonotonicRandom", WithV7MonotonicRandom()},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			now := time.UnixMilli(1645557742000)
+			g := NewGenWithOptions(WithEpochFunc(func() time.Time { return now }), tt.opt)
+
+			dst := make([]UUID, 1000)
+			if err := g.FillV7(dst); err != nil {
+				t.Fatal(err)
+			}
+			next, err := g.NewV7()
+			if err != nil {
+				t.Fatal(err)
+			}
+			checkIncreasing(t, append(dst, next), V7, 16)
+		})
+	}
+}
+
+func testFillV7AtTimeOverflow(t *testing.T) {
+	atTime := time.UnixMilli(1645557742000)
+	var events []MonotonicEvent
+	g := NewGenWithOptions(WithMonotonicHook(func(ev MonotonicEvent) {
+		events = append(events, ev)
+	}))
+
+	dst := make([]UUID, 10000)
+	if err := g.FillV7AtTime(dst, atTime); err != nil {
+		t.Fatal(err)
+	}
+	checkIncreasing(t, dst, V7, 16)
+
+	ts, err := TimestampFromV7(dst[len(dst)-1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	tm, _ := ts.Time()
+	if !tm.After(atTime) {
+		t.Errorf("last timestamp %v did not borrow from the next millisecond", tm)
+	}
+	if len(events) != 1 {
+		t.Fatalf("got %d events, want 1", len(events))
+	}
+	if ev := events[0]; !errors.Is(ev.Err, ErrClockSequenceOverflow) || ev.Policy != MonotonicBorrow {
+		t.Errorf("unexpected event %+v", ev)
+	}
+}
+
+func testFillV7Error(t *testing.T) {
+	atTime := time.UnixMilli(1645557742000)
+	g := NewGenWithOptions(WithMonotonicPolicy(MonotonicError))
+
+	dst := make([]UUID, 5000)
+	err := g.FillV7AtTime(dst, atTime)
+	if !errors.Is(err, ErrClockSequenceOverflow) {
+		t.Fatalf("FillV7AtTime() error = %v, want %v", err, ErrClockSequenceOverflow)
+	}
+	for _, u := range dst {
+		if u != Nil {
+			t.Fatalf("got %v on error, want Nil", u)
+		}
+	}
+
+	// the rejected batch did not consume the counter
+	if err := g.FillV7AtTime(dst[:100], atTime); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func testFillEmpty(t *testing.T) {
+	g := &Gen{
+		epochFunc: time.Now,
+		rand: &faultyReader{
+			readToFail: 0, // fail immediately
+		},
+	}
+	for name, fill := range map[string]func([]UUID) error{
+		"FillV4": g.FillV4,
+		"FillV6": g.FillV6,
+		"FillV7": g.FillV7,
+	} {
+		if err := fill(nil); err != nil {
+			t.Errorf("%s(nil) error = %v, want <nil>", name, err)
+		}
+	}
+}
+
+func testFillFaultyRand(t *testing.T) {
+	for name, fill := range map[string]func(*Gen, []UUID) error{
+		"FillV4": (*Gen).FillV4,
+		"FillV6": (*Gen).FillV6,
+		"FillV7": (*Gen).FillV7,
+	} {
+		for _, readToFail := range []int{0, 1} {
+			g := &Gen{
+				epochFunc: time.Now,
+				rand: &faultyReader{
+					readToFail: readToFail,
+				},
+			}
+			dst := make([]UUID, 10)
+			err := fill(g, dst)
+			if readToFail == 1 && name == "FillV4" {
+				// FillV4 reads only once
+				testErrCheck(t, name, "", err)
+				continue
+			}
+			testErrCheck(t, name, "faulty", err)
+			for _, u := range dst {
+				if u != Nil {
+					t.Fatalf("%s: got %v on error, want Nil", name, u)
+				}
+			}
+		}
+	}
+}
+
+func testFillStateless(t *testing.T) {
+	atTime := time.UnixMilli(1645557742000)
+	g := NewGen()
+	s := g.Stateless()
+
+	dst := make([]UUID, 100)
+	if err := s.FillV7AtTime(dst, atTime); err != nil {
+		t.Fatal(err)
+	}
+	checkIncreasing(t, dst, V7, 16)
+	if g.lastV7Set || s.lastV7Set {
+		t.Error("stateless batch changed the state of a generator")
+	}
+}
+
+// checkIncreasing checks that us have the provided version and the RFC 9562
+// variant, and that their first n bytes are strictly increasing.
+func checkIncreasing(t *testing.T, us []UUID, version byte, n int) {
+	t.Helper()
+	for i, u := range us {
+		if got := u.Version(); got != version {
+			t.Fatalf("%v: version = %d, want %d", u, got, version)
+		}
+		if got, want := u.Variant(), VariantRFC9562; got != want {
+			t.Fatalf("%v: variant = %d, want %d", u, got, want)
+		}
+		if i > 0 && bytes.Compare(us[i-1][:n], u[:n]) >= 0 {
+			t.Fatalf("UUID %d %v is not greater than %v", i, u, us[i-1])
+		}
+	}
+}
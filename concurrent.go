@@ -0,0 +1,141 @@
+// Copyright (C) 2013-2018 by Maxim Bublis <b@codemonkey.ru>
+//
+// Permission is hereby granted, free of charge, to any person obtaining
+// a copy of this software and associated documentation files (the
+// "Software"), to deal in the Software without restriction, including
+// without limitation the rights to use, copy, modify, merge, publish,
+// distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so, subject to
+// the following conditions:
+//
+// The above copyright notice and this permission notice shall be
+// included in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+// EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF
+// MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE
+// LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+// OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION
+// WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package uuid
+
+import (
+	"context"
+	"encoding/binary"
+	"io"
+	"sync/atomic"
+	"time"
+)
+
+// ConcurrentGen is a Generator for services that generate V7 UUIDs from many
+// goroutines at once. Instead of taking the lock of the generator, V7 UUIDs
+// are generated with an atomic compare-and-swap of the unix_ts_ms and rand_a
+// fields of the last UUID, so concurrent calls do not contend on a mutex.
+// Other versions are generated by the embedded Gen.
+//
+// V7 UUIDs from a ConcurrentGen are strictly increasing within the process.
+// rand_a is a 12-bit counter, seeded with 11 random bits every millisecond
+// as described in RFC 9562 section 6.2, Method 1, and the following 62 bits
+// are random. When the counter overflows or the clock goes backwards, the
+// generator uses the tick following the previous UUID, like MonotonicBorrow.
+// The MonotonicPolicy, the MonotonicHook, the V7 method options and the
+// StateStore of the embedded Gen do not apply to V7 UUIDs.
+type ConcurrentGen struct {
+	*Gen
+
+	// lastV7 holds the unix_ts_ms field of the last V7 UUID, shifted left
+	// by 12 bits and combined with its rand_a field.
+	lastV7 atomic.Uint64
+}
+
+// interface check -- build will fail if *ConcurrentGen doesn't satisfy Generator
+var _ Generator = (*ConcurrentGen)(nil)
+
+// interface check -- build will fail if *ConcurrentGen doesn't satisfy BatchGenerator
+var _ BatchGenerator = (*ConcurrentGen)(nil)
+
+// NewConcurrentGen returns a ConcurrentGen configured with the provided
+// GenOption functions, as NewGenWithOptions does.
+func NewConcurrentGen(opts ...GenOption) *ConcurrentGen {
+	return &ConcurrentGen{Gen: NewGenWithOptions(opts...)}
+}
+
+// NewV7 returns a k-sortable UUID based on the current millisecond-precision
+// UNIX epoch and 74 bits of pseudorandom data.
+func (g *ConcurrentGen) NewV7() (UUID, error) {
+	var u [1]UUID
+	if err := g.fillV7(u[:], g.epochFunc()); err != nil {
+		return Nil, err
+	}
+	return u[0], nil
+}
+
+// NewV7Context is like NewV7. A ConcurrentGen never waits for the clock, so
+// ctx is not used.
+func (g *ConcurrentGen) NewV7Context(ctx context.Context) (UUID, error) {
+	return g.NewV7()
+}
+
+// NewV7AtTime returns a k-sortable UUID based on the provided
+// millisecond-precision UNIX epoch and 74 bits of pseudorandom data.
+func (g *ConcurrentGen) NewV7AtTime(atTime time.Time) (UUID, error) {
+	var u [1]UUID
+	if err := g.fillV7(u[:], atTime); err != nil {
+		return Nil, err
+	}
+	return u[0], nil
+}
+
+// FillV7 fills dst with V7 UUIDs based on the current timestamp, reserving
+// the counter values for the whole batch with a single compare-and-swap.
+// On error, dst is left unchanged.
+func (g *ConcurrentGen) FillV7(dst []UUID) error {
+	return g.fillV7(dst, g.epochFunc())
+}
+
+// FillV7AtTime is like FillV7, but for the provided timestamp.
+func (g *ConcurrentGen) FillV7AtTime(dst []UUID, atTime time.Time) error {
+	return g.fillV7(dst, atTime)
+}
+
+func (g *ConcurrentGen) fillV7(dst []UUID, atTime time.Time) error {
+	if len(dst) == 0 {
+		return nil
+	}
+	atTime = g.embeddedTime(atTime)
+	if err := checkTimeRange(V7, atTime); err != nil {
+		return err
+	}
+
+	// The first two bytes seed the counter, followed by rand_b for every
+	// UUID. Reading them before the compare-and-swap keeps the loop short.
+	var arr [2 + 8]byte
+	buf := arr[:]
+	if len(dst) > 1 {
+		buf = make([]byte, 2+8*len(dst))
+	}
+	if _, err := io.ReadFull(g.rand, buf); err != nil {
+		return err
+	}
+	seed := uint64(binary.BigEndian.Uint16(buf) & 0x7ff)
+	ts := uint64(atTime.UnixMilli())<<12 | seed
+
+	n := uint64(len(dst))
+	var first uint64
+	for {
+		last := g.lastV7.Load()
+		first = max(ts, last+1)
+		if g.lastV7.CompareAndSwap(last, first+n-1) {
+			break
+		}
+	}
+
+	for i := range dst {
+		u := &dst[i]
+		copy(u[8:], buf[2+8*i:])
+		encodeV7Time(u, first+uint64(i))
+	}
+	return nil
+}
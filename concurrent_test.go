@@ -0,0 +1,196 @@
+// Copyright (C) 2013-2018 by Maxim Bublis <b@codemonkey.ru>
+//
+// Permission is hereby granted, free of charge, to any person obtaining
+// a copy of this software and associated documentation files (the
+// "Software"), to deal in the Software without restriction, including
+// without limitation the rights to use, copy, modify, merge, publish,
+// distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so, subject to
+// the following conditions:
+//
+// The above copyright notice and this permission notice shall be
+// included in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+// EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF
+// MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE
+// LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+// OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION
+// WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package uuid
+
+import (
+	"bytes"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestConcurrentGen(t *testing.T) {
+	t.Run("Parallel", testConcurrentGenParallel)
+	t.Run("Overflow", testConcurrentGenOverflow)
+	t.Run("Rollback", testConcurrentGenRollback)
+	t.Run("Fill", testConcurrentGenFill)
+	t.Run("FaultyRand", testConcurrentGenFaultyRand)
+	t.Run("TimeRange", testConcurrentGenTimeRange)
+	t.Run("OtherVersions", testConcurrentGenOtherVersions)
+}
+
+func testConcurrentGenParallel(t *testing.T) {
+	g := NewConcurrentGen()
+
+	const goroutines, perGoroutine = 8, 1000
+	results := make([][]UUID, goroutines)
+	var wg sync.WaitGroup
+	for i := range results {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			us := make([]UUID, perGoroutine)
+			for j := range us {
+				u, err := g.NewV7()
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				us[j] = u
+			}
+			results[i] = us
+		}()
+	}
+	wg.Wait()
+
+	seen := make(map[UUID]bool, goroutines*perGoroutine)
+	for _, us := range results {
+		checkIncreasing(t, us, V7, 8)
+		for _, u := range us {
+			if seen[u] {
+				t.Fatalf("generated %v twice", u)
+			}
+			seen[u] = true
+		}
+	}
+}
+
+func testConcurrentGenOverflow(t *testing.T) {
+	now := time.UnixMilli(1645557742000)
+	g := NewConcurrentGen(WithEpochFunc(func() time.Time { return now }))
+
+	us := make([]UUID, 10000)
+	for i := range us {
+		u, err := g.NewV7()
+		if err != nil {
+			t.Fatal(err)
+		}
+		us[i] = u
+	}
+	checkIncreasing(t, us, V7, 8)
+
+	ts, err := TimestampFromV7(us[len(us)-1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	tm, _ := ts.Time()
+	if !tm.After(now) {
+		t.Errorf("last timestamp %v did not borrow from the next millisecond", tm)
+	}
+}
+
+func testConcurrentGenRollback(t *testing.T) {
+	atTime := time.UnixMilli(1645557742000)
+	g := NewConcurrentGen()
+	u1, err := g.NewV7AtTime(atTime)
+	if err != nil {
+		t.Fatal(err)
+	}
+	u2, err := g.NewV7AtTime(atTime.Add(-time.Second))
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkIncreasing(t, []UUID{u1, u2}, V7, 8)
+}
+
+func testConcurrentGenFill(t *testing.T) {
+	now := time.UnixMilli(1645557742000)
+	g := NewConcurrentGen(WithEpochFunc(func() time.Time { return now }))
+
+	us := make([]UUID, 5000)
+	if err := g.FillV7(us[:4999]); err != nil {
+		t.Fatal(err)
+	}
+	u, err := g.NewV7()
+	if err != nil {
+		t.Fatal(err)
+	}
+	us[4999] = u
+	checkIncreasing(t, us, V7, 8)
+
+	if err := g.FillV7AtTime(nil, now); err != nil {
+		t.Errorf("FillV7AtTime(nil) error = %v, want <nil>", err)
+	}
+}
+
+func testConcurrentGenFaultyRand(t *testing.T) {
+	g := NewConcurrentGen(WithRandomReader(&faultyReader{readToFail: 0}))
+	u, err := g.NewV7()
+	testErrCheck(t, "NewV7()", "faulty", err)
+	if u != Nil {
+		t.Errorf("got %v on error, want Nil", u)
+	}
+	if g.lastV7.Load() != 0 {
+		t.Error("failed call changed the state of the generator")
+	}
+}
+
+func testConcurrentGenTimeRange(t *testing.T) {
+	g := NewConcurrentGen()
+	_, err := g.NewV7AtTime(MinTimeV7.Add(-time.Millisecond))
+	if !errors.Is(err, ErrTimeOutOfRange) {
+		t.Errorf("NewV7AtTime() error = %v, want %v", err, ErrTimeOutOfRange)
+	}
+}
+
+func testConcurrentGenOtherVersions(t *testing.T) {
+	g := NewConcurrentGen()
+	var gen Generator = g
+	u, err := gen.NewV4()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := u.Version(), V4; got != want {
+		t.Errorf("version = %d, want %d", got, want)
+	}
+	u1, err := gen.NewV7()
+	if err != nil {
+		t.Fatal(err)
+	}
+	u2, err := g.NewV7Context(t.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Compare(u1[:], u2[:]) >= 0 {
+		t.Errorf("UUID %v is not greater than %v", u2, u1)
+	}
+}
+
+func BenchmarkConcurrentGen(b *testing.B) {
+	b.Run("Gen", func(b *testing.B) {
+		g := NewGen()
+		b.RunParallel(func(pb *testing.PB) {
+			for pb.Next() {
+				_, _ = g.NewV7()
+			}
+		})
+	})
+	b.Run("ConcurrentGen", func(b *testing.B) {
+		g := NewConcurrentGen()
+		b.RunParallel(func(pb *testing.PB) {
+			for pb.Next() {
+				_, _ = g.NewV7()
+			}
+		})
+	})
+}
@@ -37,6 +37,40 @@ const (
 
 	// ErrV8FieldLength indicates a V8 custom field has incorrect length.
 	ErrV8FieldLength = Error("uuid: V8 field has incorrect length")
+
+	// ErrHashTooShort indicates a hash function whose output is shorter than
+	// a UUID.
+	ErrHashTooShort = Error("uuid: hash is shorter than 16 bytes")
+
+	// ErrInvalidV8Layout indicates a V8Layout with invalid fields.
+	ErrInvalidV8Layout = Error("uuid: invalid V8 layout")
+
+	// ErrInvalidTimeV8Config indicates a TimeV8Config with invalid values.
+	ErrInvalidTimeV8Config = Error("uuid: invalid time-based V8 configuration")
+
+	// ErrV8FieldValue indicates a value that cannot be encoded in a field of
+	// a V8Layout.
+	ErrV8FieldValue = Error("uuid: invalid V8 field value")
+
+	// ErrStateNotFound is returned by a StateStore when no generator state
+	// has been saved yet.
+	ErrStateNotFound = Error("uuid: no saved generator state")
+
+	// ErrClockSequenceOverflow indicates that the counter of a time-based
+	// UUID is exhausted for the current clock tick.
+	ErrClockSequenceOverflow = Error("uuid: clock sequence overflow")
+
+	// ErrClockRollback indicates that the clock moved backwards since the
+	// previous time-based UUID was generated.
+	ErrClockRollback = Error("uuid: clock moved backwards")
+
+	// ErrTimeOutOfRange indicates that a time cannot be encoded in the
+	// timestamp of the requested UUID version.
+	ErrTimeOutOfRange = Error("uuid: time out of range")
+
+	// ErrNoLocalID is returned when the local identifier of a DCE domain
+	// cannot be found for V2 UUID generation.
+	ErrNoLocalID = Error("uuid: no local ID found")
 )
 
 // Wrapped errors for backward compatibility. These wrap ErrIncorrectFormatInString
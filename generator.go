@@ -22,14 +22,19 @@
 package uuid
 
 import (
+	"context"
 	"crypto/md5"
 	"crypto/rand"
 	"crypto/sha1"
 	"encoding/binary"
+	"errors"
 	"fmt"
 	"io"
 	"net"
+	"os"
+	"runtime"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -37,6 +42,29 @@ import (
 // UUID epoch (October 15, 1582) and Unix epoch (January 1, 1970).
 const epochStart = 122192928000000000
 
+// Range of times that can be encoded in time-based UUIDs. The timestamp of V1
+// and V6 UUIDs is a 60-bit count of 100-nanosecond intervals since the
+// Gregorian reform, and the one of V7 UUIDs a 48-bit count of milliseconds
+// since the Unix epoch. Times within these bounds are truncated to the
+// precision of the version. Assigning to these variables does not change the
+// range of times accepted by the generators.
+var (
+	MinTimeV1 = minTimeV1
+	MaxTimeV1 = maxTimeV1
+	MinTimeV6 = minTimeV1
+	MaxTimeV6 = maxTimeV1
+	MinTimeV7 = minTimeV7
+	MaxTimeV7 = maxTimeV7
+)
+
+// The range of times checked by checkTimeRange.
+var (
+	minTimeV1 = time.Date(1582, time.October, 15, 0, 0, 0, 0, time.UTC)
+	maxTimeV1 = time.Date(5236, time.March, 31, 21, 21, 0, 684697599, time.UTC)
+	minTimeV7 = time.Date(1970, time.January, 1, 0, 0, 0, 0, time.UTC)
+	maxTimeV7 = time.Date(10889, time.August, 2, 5, 31, 50, 655999999, time.UTC)
+)
+
 // EpochFunc is the function type used to provide the current time.
 type EpochFunc func() time.Time
 
@@ -56,11 +84,59 @@ func NewV1AtTime(atTime time.Time) (UUID, error) {
 	return DefaultGenerator.NewV1AtTime(atTime)
 }
 
+// NewV2 returns a DCE Security UUID based on the current timestamp, MAC
+// address, and the provided DCE domain and local ID. It uses the NewV2 method
+// of DefaultGenerator, such as Gen.NewV2, and returns an error wrapping
+// ErrInvalidVersion when DefaultGenerator has none.
+func NewV2(domain byte, id uint32) (UUID, error) {
+	g, ok := DefaultGenerator.(v2Generator)
+	if !ok {
+		return Nil, fmt.Errorf("%w DefaultGenerator %T does not generate version 2 UUIDs", ErrInvalidVersion, DefaultGenerator)
+	}
+	return g.NewV2(domain, id)
+}
+
+// NewV2Local returns a DCE Security UUID like NewV2, with the local ID of
+// the current process for the domain: its POSIX UID for DomainPerson, or
+// its POSIX GID for DomainGroup.
+func NewV2Local(domain byte) (UUID, error) {
+	id, err := localID(domain)
+	if err != nil {
+		return Nil, err
+	}
+	return NewV2(domain, id)
+}
+
+// v2Generator is implemented by generators of V2 UUIDs, such as Gen. It is
+// not part of Generator, so that implementations of Generator without V2
+// UUIDs keep satisfying it.
+type v2Generator interface {
+	NewV2(domain byte, id uint32) (UUID, error)
+}
+
 // NewV3 returns a UUID based on the MD5 hash of the namespace UUID and name.
 func NewV3(ns UUID, name string) UUID {
 	return DefaultGenerator.NewV3(ns, name)
 }
 
+// NewV3Bytes returns a UUID based on the MD5 hash of the namespace UUID and
+// name, like NewV3, with a binary name.
+func NewV3Bytes(ns UUID, name []byte) UUID {
+	if g, ok := DefaultGenerator.(NameGenerator); ok {
+		return g.NewV3Bytes(ns, name)
+	}
+	return newV3Bytes(ns, name)
+}
+
+// NewV3Reader returns a UUID based on the MD5 hash of the namespace UUID and
+// the name read from r until EOF. Returns the error of r, if any.
+func NewV3Reader(ns UUID, r io.Reader) (UUID, error) {
+	if g, ok := DefaultGenerator.(NameGenerator); ok {
+		return g.NewV3Reader(ns, r)
+	}
+	return newV3Reader(ns, r)
+}
+
 // NewV4 returns a randomly generated UUID.
 func NewV4() (UUID, error) {
 	return DefaultGenerator.NewV4()
@@ -71,6 +147,24 @@ func NewV5(ns UUID, name string) UUID {
 	return DefaultGenerator.NewV5(ns, name)
 }
 
+// NewV5Bytes returns a UUID based on the SHA-1 hash of the namespace UUID and
+// name, like NewV5, with a binary name.
+func NewV5Bytes(ns UUID, name []byte) UUID {
+	if g, ok := DefaultGenerator.(NameGenerator); ok {
+		return g.NewV5Bytes(ns, name)
+	}
+	return newV5Bytes(ns, name)
+}
+
+// NewV5Reader returns a UUID based on the SHA-1 hash of the namespace UUID and
+// the name read from r until EOF. Returns the error of r, if any.
+func NewV5Reader(ns UUID, r io.Reader) (UUID, error) {
+	if g, ok := DefaultGenerator.(NameGenerator); ok {
+		return g.NewV5Reader(ns, r)
+	}
+	return newV5Reader(ns, r)
+}
+
 // NewV6 returns a k-sortable UUID based on the current timestamp and 48 bits of
 // pseudorandom data. The timestamp in a V6 UUID is the same as V1, with the bit
 // order being adjusted to allow the UUID to be k-sortable.
@@ -125,6 +219,16 @@ type Generator interface {
 	NewV8([]byte, []byte, []byte) (UUID, error)
 }
 
+// NameGenerator is implemented by generators of name-based UUIDs from binary
+// names or names read from an io.Reader. When DefaultGenerator does not
+// implement it, the package-level functions hash the names directly.
+type NameGenerator interface {
+	NewV3Bytes(ns UUID, name []byte) UUID
+	NewV3Reader(ns UUID, r io.Reader) (UUID, error)
+	NewV5Bytes(ns UUID, name []byte) UUID
+	NewV5Reader(ns UUID, r io.Reader) (UUID, error)
+}
+
 // Gen is a reference UUID generator based on the specifications laid out in
 // RFC-9562 and DCE 1.1: Authentication and Security Services. This type
 // satisfies the Generator interface as defined in this package.
@@ -138,24 +242,82 @@ type Generator interface {
 // a new generator.
 type Gen struct {
 	clockSequenceOnce sync.Once
-	hardwareAddrOnce  sync.Once
+	hardwareAddrMutex sync.Mutex
 	storageMutex      sync.Mutex
 
-	rand io.Reader
+	rand           io.Reader
+	randomPoolSize int
 
 	epochFunc     EpochFunc
 	hwAddrFunc    HWAddrFunc
 	lastTime      uint64
+	lastClock     uint64
 	clockSequence uint16
-	hardwareAddr  [6]byte
+	simulateTicks bool
+
+	timestampOffset time.Duration
+	timestampFuzz   time.Duration
+
+	// node is the node of V1 and V2 UUIDs, replaced under
+	// hardwareAddrMutex, and clockSequenceNode the generation of the node
+	// the clock sequence was last used with.
+	node              atomic.Pointer[nodeState]
+	nodeRotation      time.Duration
+	clockSequenceNode atomic.Uint64
+
+	v7Method      v7Method
+	v7CounterBits int
+	lastV7        uint64
+	lastV7RandB   uint64
+	lastV7Set     bool
+	lastV7Clock   uint64
+
+	monotonicPolicy MonotonicPolicy
+	monotonicHook   func(MonotonicEvent)
+
+	parent       *Gen
+	stateless    bool
+	streamsMutex sync.Mutex
+	streams      map[string]*Gen
+
+	stateStore        StateStore
+	stateSaveInterval time.Duration
+	stateSavedAt      time.Time
+	stateDirty        bool
+	stateErr          error
 }
 
 // GenOption is a function type that can be used to configure a Gen generator.
 type GenOption func(*Gen)
 
+// v7Method selects how the 74 bits following unix_ts_ms in a V7 UUID keep
+// UUIDs generated within the same millisecond in order.
+type v7Method uint8
+
+const (
+	// rand_a is a counter seeded from the clock sequence and never
+	// reseeded, with its most significant bit cleared when a millisecond
+	// starts; rand_b is random.
+	v7MethodClockSequence v7Method = iota
+
+	// rand_a is the fraction of the millisecond; rand_b is random.
+	v7MethodSubMillisecond
+
+	// rand_a and the leading bits of rand_b are a counter reseeded every
+	// millisecond; the rest of rand_b is random.
+	v7MethodCounter
+
+	// rand_a and rand_b are a random number reseeded every millisecond and
+	// incremented by a random amount.
+	v7MethodMonotonicRandom
+)
+
 // interface check -- build will fail if *Gen doesn't satisfy Generator
 var _ Generator = (*Gen)(nil)
 
+// interface check -- build will fail if *Gen doesn't satisfy NameGenerator
+var _ NameGenerator = (*Gen)(nil)
+
 // NewGen returns a new instance of Gen with some default values set. Most
 // people should use this.
 func NewGen() *Gen {
@@ -169,10 +331,12 @@ func NewGen() *Gen {
 // in the generated UUIDs, if there is some concern about exposing the physical
 // address of the machine generating the UUID.
 //
-// The Gen generator will only invoke the HWAddrFunc once, and cache that MAC
-// address for all the future UUIDs generated by it. If you'd like to switch the
-// MAC address being used, you'll need to create a new generator using this
-// function.
+// The Gen generator invokes the HWAddrFunc the first time it needs a MAC
+// address, and caches it for all the future UUIDs generated by it. When the
+// HWAddrFunc fails, a random MAC address is used, and the HWAddrFunc is
+// invoked again after a minute. If you'd like to switch the MAC address being
+// used, call ResetNode to invoke the HWAddrFunc again, or use WithNodeRotation
+// to switch to random MAC addresses on a schedule.
 func NewGenWithHWAF(hwaf HWAddrFunc) *Gen {
 	return NewGenWithOptions(WithHWAddrFunc(hwaf))
 }
@@ -202,6 +366,9 @@ func NewGenWithOptions(opts ...GenOption) *Gen {
 	for _, opt := range opts {
 		opt(gen)
 	}
+	if gen.randomPoolSize > 0 {
+		gen.rand = newRandomPool(gen.rand, gen.randomPoolSize)
+	}
 
 	return gen
 }
@@ -245,16 +412,185 @@ func WithRandomReader(reader io.Reader) GenOption {
 	}
 }
 
+// WithStateStore is a GenOption that keeps the clock sequence and the last
+// timestamp of the generator in stable storage, as described in RFC 9562
+// section 6.3.
+//
+// The state is loaded from store before the first time-based UUID is
+// generated. If no state can be loaded, the clock sequence is initialized
+// randomly as usual. Afterwards, the state is saved at most once every
+// interval, and when Close is called. An interval of zero or less saves the
+// state after every time-based UUID.
+//
+// Restoring the state means that a generator that restarts after the wall
+// clock moved backwards will increment its clock sequence, instead of
+// picking one at random that may collide with a previous run. Since the
+// state may be up to interval old after a crash, callers should choose an
+// interval that is small compared to the clock adjustments they expect.
+//
+// When this option is nil, no state is kept.
+func WithStateStore(store StateStore, interval time.Duration) GenOption {
+	return func(gen *Gen) {
+		gen.stateStore = store
+		gen.stateSaveInterval = interval
+	}
+}
+
+// WithSimulatedTicks is a GenOption that makes V1 and V6 UUIDs from the
+// generator strictly increasing, by simulating a clock with a resolution of
+// 100ns. When the clock has not advanced since the previous UUID, as is
+// common with coarse clocks, the timestamp of the previous UUID is
+// incremented instead of the clock sequence. This follows the "System Clock
+// Resolution" section of RFC 4122, and the guidance of RFC 9562 section 6.2
+// on increasing clock precision.
+//
+// The timestamp may run ahead of the clock while more than one UUID per
+// 100ns is generated, until the clock catches up. With MonotonicWrap, a
+// clock going backwards is handled like MonotonicBorrow.
+func WithSimulatedTicks() GenOption {
+	return func(gen *Gen) {
+		gen.simulateTicks = true
+	}
+}
+
+// WithTimestampOffset is a GenOption that shifts the time embedded in V1,
+// V2, V6 and V7 UUIDs by d, as allowed by RFC 9562 section 6.1, so the time
+// a UUID was generated cannot be read from it without knowing d. The UUIDs
+// keep their order, and holders of the offset recover the true time with
+// the TimestampFromV1, TimestampFromV6 and TimestampFromV7 methods of a Gen
+// with the same offset. d is truncated to a multiple of 100 nanoseconds, the
+// precision of V1 and V6 timestamps. A time that is out of range once
+// shifted returns an error wrapping ErrTimeOutOfRange.
+func WithTimestampOffset(d time.Duration) GenOption {
+	return func(gen *Gen) {
+		gen.timestampOffset = d.Truncate(100 * time.Nanosecond)
+	}
+}
+
+// WithTimestampFuzz is a GenOption that truncates the time embedded in V1,
+// V2, V6 and V7 UUIDs to a multiple of granularity, after any offset, as
+// allowed by RFC 9562 section 6.1, so the time a UUID was generated cannot be
+// recovered more precisely than granularity.
+//
+// UUIDs from the generator stay strictly increasing: V1, V2 and V6 UUIDs
+// within the same granule get increasing timestamps, as with
+// WithSimulatedTicks, and the counter of V7 UUIDs borrows the following
+// milliseconds when it overflows, with MonotonicWrap behaving like
+// MonotonicBorrow. A granularity of 0 or less disables fuzzing.
+func WithTimestampFuzz(granularity time.Duration) GenOption {
+	return func(gen *Gen) {
+		gen.timestampFuzz = granularity
+	}
+}
+
+// WithNodeRotation is a GenOption that makes the generator use random nodes
+// for V1 and V2 UUIDs instead of the node of its HWAddrFunc, and replace the
+// node with a new random one once interval has elapsed, according to its
+// EpochFunc. The clock sequence is re-randomized with every new node, so
+// UUIDs from different intervals cannot be linked to the same generator. An
+// interval of 0 or less disables rotation.
+func WithNodeRotation(interval time.Duration) GenOption {
+	return func(gen *Gen) {
+		gen.nodeRotation = interval
+	}
+}
+
+// WithV7SubMillisecond is a GenOption that makes the generator fill the
+// rand_a field of V7 UUIDs with the fraction of the millisecond, as described
+// in RFC 9562 section 6.2, Method 3. This is the same layout as the uuidv7()
+// function of PostgreSQL 18.
+//
+// The fraction is stored with a precision of 1/4096 ms (about 244ns), and
+// UUIDs generated within the same 1/4096 ms are ordered by incrementing the
+// previous value. Since the ordering relies on the timestamp alone, UUIDs
+// from several generators interleave by creation time. Use
+// TimestampFromV7SubMillisecond to recover the finer timestamp.
+func WithV7SubMillisecond() GenOption {
+	return func(gen *Gen) {
+		gen.v7Method = v7MethodSubMillisecond
+	}
+}
+
+// WithV7Counter is a GenOption that makes the generator use a dedicated
+// counter of the provided number of bits in V7 UUIDs, as described in
+// RFC 9562 section 6.2, Method 1 (Fixed Bit-Length Dedicated Counter). The
+// counter fills rand_a and, beyond 12 bits, the leading bits of rand_b. The
+// rest of rand_b stays random.
+//
+// The counter is reseeded with a random value every millisecond, with its
+// most significant bit cleared as a rollover guard, so at least 2^(bits-1)
+// UUIDs can be generated within a millisecond. Reseeding also keeps the
+// counter from revealing how many UUIDs were generated over time.
+//
+// bits is clamped between 12 and 42.
+func WithV7Counter(bits int) GenOption {
+	return func(gen *Gen) {
+		gen.v7Method = v7MethodCounter
+		gen.v7CounterBits = min(max(bits, 12), 42)
+	}
+}
+
+// WithV7MonotonicRandom is a GenOption that makes the generator treat the 74
+// bits of rand_a and rand_b in V7 UUIDs as a single random number, which is
+// reseeded every millisecond and incremented by a random amount between 1
+// and 2^32 for every UUID within the millisecond, as described in RFC 9562
+// section 6.2, Method 2 (Monotonic Random).
+func WithV7MonotonicRandom() GenOption {
+	return func(gen *Gen) {
+		gen.v7Method = v7MethodMonotonicRandom
+	}
+}
+
+// Close saves the current generator state to the StateStore configured with
+// WithStateStore. It returns the first error encountered while loading or
+// periodically saving the state since the last call to Close, if any.
+//
+// Close is a no-op when no StateStore is configured. The generator remains
+// usable after Close.
+func (g *Gen) Close() error {
+	if g.stateStore == nil {
+		return nil
+	}
+
+	g.storageMutex.Lock()
+	defer g.storageMutex.Unlock()
+
+	err := g.stateErr
+	g.stateErr = nil
+	if g.stateDirty {
+		err = errors.Join(err, g.saveState())
+	}
+	return err
+}
+
 // NewV1 returns a UUID based on the current timestamp and MAC address.
 func (g *Gen) NewV1() (UUID, error) {
-	return g.NewV1AtTime(g.epochFunc())
+	return g.newV1(context.Background(), g.epochFunc(), true)
 }
 
 // NewV1AtTime returns a UUID based on the provided timestamp and current MAC address.
 func (g *Gen) NewV1AtTime(atTime time.Time) (UUID, error) {
+	return g.newV1(context.Background(), atTime, false)
+}
+
+func (g *Gen) newV1(ctx context.Context, atTime time.Time, now bool) (UUID, error) {
+	if g.stateless {
+		return g.child().newV1(ctx, atTime, now)
+	}
+
 	u := UUID{}
 
-	timeNow, clockSeq, err := g.getClockSequence(false, atTime)
+	atTime = g.embeddedTime(atTime)
+	if err := checkTimeRange(V1, atTime); err != nil {
+		return Nil, err
+	}
+
+	// The node is looked up first, as a new node changes the clock sequence.
+	if err := g.putNode(u[10:]); err != nil {
+		return Nil, err
+	}
+
+	timeNow, clockSeq, err := g.getClockSequence(ctx, V1, atTime, now, 1)
 	if err != nil {
 		return Nil, err
 	}
@@ -263,24 +599,113 @@ func (g *Gen) NewV1AtTime(atTime time.Time) (UUID, error) {
 	binary.BigEndian.PutUint16(u[6:], uint16(timeNow>>48))
 	binary.BigEndian.PutUint16(u[8:], clockSeq)
 
-	hardwareAddr, err := g.getHardwareAddr()
+	u.SetVersion(V1)
+	u.SetVariant(VariantRFC9562)
+
+	return u, nil
+}
+
+// NewV2 returns a DCE Security UUID based on the current timestamp, MAC
+// address, and the provided DCE domain and local ID, as described in DCE 1.1:
+// Authentication and Security Services. The local ID replaces the time_low
+// field, and the domain replaces the low byte of the clock sequence.
+//
+// Since only the upper 28 bits of the timestamp and 6 bits of the clock
+// sequence are kept, V2 UUIDs for the same domain and local ID only differ
+// once every 2^32 intervals of 100ns (about 7 minutes), unless the clock
+// sequence changes.
+func (g *Gen) NewV2(domain byte, id uint32) (UUID, error) {
+	return g.newV2(context.Background(), domain, id, g.epochFunc(), true)
+}
+
+// NewV2Local returns a DCE Security UUID like NewV2, with the local ID of
+// the current process for the domain: its POSIX UID for DomainPerson, or
+// its POSIX GID for DomainGroup. Returns an error wrapping ErrNoLocalID for
+// other domains, or when the platform has no POSIX IDs.
+func (g *Gen) NewV2Local(domain byte) (UUID, error) {
+	id, err := localID(domain)
 	if err != nil {
 		return Nil, err
 	}
-	copy(u[10:], hardwareAddr)
+	return g.NewV2(domain, id)
+}
 
-	u.SetVersion(V1)
+func (g *Gen) newV2(ctx context.Context, domain byte, id uint32, atTime time.Time, now bool) (UUID, error) {
+	if g.stateless {
+		return g.child().newV2(ctx, domain, id, atTime, now)
+	}
+
+	u := UUID{}
+
+	atTime = g.embeddedTime(atTime)
+	if err := checkTimeRange(V2, atTime); err != nil {
+		return Nil, err
+	}
+
+	// The node is looked up first, as a new node changes the clock sequence.
+	if err := g.putNode(u[10:]); err != nil {
+		return Nil, err
+	}
+
+	timeNow, clockSeq, err := g.getClockSequence(ctx, V2, atTime, now, 1)
+	if err != nil {
+		return Nil, err
+	}
+	binary.BigEndian.PutUint32(u[0:], id)
+	binary.BigEndian.PutUint16(u[4:], uint16(timeNow>>32))
+	binary.BigEndian.PutUint16(u[6:], uint16(timeNow>>48))
+	u[8] = byte(clockSeq >> 8)
+	u[9] = domain
+
+	u.SetVersion(V2)
 	u.SetVariant(VariantRFC9562)
 
 	return u, nil
 }
 
+// localID returns the POSIX UID or GID of the current process for the
+// provided DCE domain.
+func localID(domain byte) (uint32, error) {
+	var id int
+	switch domain {
+	case DomainPerson:
+		id = os.Getuid()
+	case DomainGroup:
+		id = os.Getgid()
+	default:
+		return 0, fmt.Errorf("%w for domain %d", ErrNoLocalID, domain)
+	}
+	if id < 0 {
+		return 0, fmt.Errorf("%w for domain %d on %s", ErrNoLocalID, domain, runtime.GOOS)
+	}
+	return uint32(id), nil
+}
+
 // NewV3 returns a UUID based on the MD5 hash of the namespace UUID and name.
-func (g *Gen) NewV3(ns UUID, name string) (u UUID) {
+func (g *Gen) NewV3(ns UUID, name string) UUID {
+	return g.NewV3Bytes(ns, []byte(name))
+}
+
+// NewV3Bytes returns a UUID based on the MD5 hash of the namespace UUID and
+// name. It does not allocate.
+func (g *Gen) NewV3Bytes(ns UUID, name []byte) UUID {
+	return newV3Bytes(ns, name)
+}
+
+// NewV3Reader returns a UUID based on the MD5 hash of the namespace UUID and
+// the name read from r until EOF. Returns the error of r, if any.
+func (g *Gen) NewV3Reader(ns UUID, r io.Reader) (UUID, error) {
+	return newV3Reader(ns, r)
+}
+
+// newV3Bytes returns a UUID based on the MD5 hash of the namespace UUID and
+// name.
+func newV3Bytes(ns UUID, name []byte) (u UUID) {
 	h := md5.New()
 	h.Write(ns[:])
-	h.Write([]byte(name))
-	copy(u[:], h.Sum(make([]byte, 0, md5.Size)))
+	h.Write(name)
+	var sum [md5.Size]byte
+	copy(u[:], h.Sum(sum[:0]))
 
 	u.SetVersion(V3)
 	u.SetVariant(VariantRFC9562)
@@ -288,6 +713,24 @@ func (g *Gen) NewV3(ns UUID, name string) (u UUID) {
 	return u
 }
 
+// newV3Reader returns a UUID based on the MD5 hash of the namespace UUID and
+// the name read from r until EOF.
+func newV3Reader(ns UUID, r io.Reader) (UUID, error) {
+	h := md5.New()
+	h.Write(ns[:])
+	if _, err := io.Copy(h, r); err != nil {
+		return Nil, err
+	}
+	var u UUID
+	var sum [md5.Size]byte
+	copy(u[:], h.Sum(sum[:0]))
+
+	u.SetVersion(V3)
+	u.SetVariant(VariantRFC9562)
+
+	return u, nil
+}
+
 // NewV4 returns a randomly generated UUID.
 func (g *Gen) NewV4() (UUID, error) {
 	u := UUID{}
@@ -301,11 +744,30 @@ func (g *Gen) NewV4() (UUID, error) {
 }
 
 // NewV5 returns a UUID based on SHA-1 hash of the namespace UUID and name.
-func (g *Gen) NewV5(ns UUID, name string) (u UUID) {
+func (g *Gen) NewV5(ns UUID, name string) UUID {
+	return g.NewV5Bytes(ns, []byte(name))
+}
+
+// NewV5Bytes returns a UUID based on the SHA-1 hash of the namespace UUID and
+// name. It does not allocate.
+func (g *Gen) NewV5Bytes(ns UUID, name []byte) UUID {
+	return newV5Bytes(ns, name)
+}
+
+// NewV5Reader returns a UUID based on the SHA-1 hash of the namespace UUID and
+// the name read from r until EOF. Returns the error of r, if any.
+func (g *Gen) NewV5Reader(ns UUID, r io.Reader) (UUID, error) {
+	return newV5Reader(ns, r)
+}
+
+// newV5Bytes returns a UUID based on the SHA-1 hash of the namespace UUID and
+// name.
+func newV5Bytes(ns UUID, name []byte) (u UUID) {
 	h := sha1.New()
 	h.Write(ns[:])
-	h.Write([]byte(name))
-	copy(u[:], h.Sum(make([]byte, 0, sha1.Size)))
+	h.Write(name)
+	var sum [sha1.Size]byte
+	copy(u[:], h.Sum(sum[:0]))
 
 	u.SetVersion(V5)
 	u.SetVariant(VariantRFC9562)
@@ -313,17 +775,43 @@ func (g *Gen) NewV5(ns UUID, name string) (u UUID) {
 	return u
 }
 
+// newV5Reader returns a UUID based on the SHA-1 hash of the namespace UUID and
+// the name read from r until EOF.
+func newV5Reader(ns UUID, r io.Reader) (UUID, error) {
+	h := sha1.New()
+	h.Write(ns[:])
+	if _, err := io.Copy(h, r); err != nil {
+		return Nil, err
+	}
+	var u UUID
+	var sum [sha1.Size]byte
+	copy(u[:], h.Sum(sum[:0]))
+
+	u.SetVersion(V5)
+	u.SetVariant(VariantRFC9562)
+
+	return u, nil
+}
+
 // NewV6 returns a k-sortable UUID based on the current timestamp and 48 bits of
 // pseudorandom data. The timestamp in a V6 UUID is the same as V1, with the bit
 // order being adjusted to allow the UUID to be k-sortable.
 func (g *Gen) NewV6() (UUID, error) {
-	return g.NewV6AtTime(g.epochFunc())
+	return g.newV6(context.Background(), g.epochFunc(), true)
 }
 
 // NewV6 returns a k-sortable UUID based on the provided timestamp and 48 bits of
 // pseudorandom data. The timestamp in a V6 UUID is the same as V1, with the bit
 // order being adjusted to allow the UUID to be k-sortable.
 func (g *Gen) NewV6AtTime(atTime time.Time) (UUID, error) {
+	return g.newV6(context.Background(), atTime, false)
+}
+
+func (g *Gen) newV6(ctx context.Context, atTime time.Time, now bool) (UUID, error) {
+	if g.stateless {
+		return g.child().newV6(ctx, atTime, now)
+	}
+
 	/* https://datatracker.ietf.org/doc/html/rfc9562#name-uuid-version-6
 	    0                   1                   2                   3
 	    0 1 2 3 4 5 6 7 8 9 0 1 2 3 4 5 6 7 8 9 0 1 2 3 4 5 6 7 8 9 0 1
@@ -338,14 +826,15 @@ func (g *Gen) NewV6AtTime(atTime time.Time) (UUID, error) {
 	   +-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+ */
 	var u UUID
 
-	timeNow, _, err := g.getClockSequence(false, atTime)
-	if err != nil {
+	atTime = g.embeddedTime(atTime)
+	if err := checkTimeRange(V6, atTime); err != nil {
 		return Nil, err
 	}
 
-	binary.BigEndian.PutUint32(u[0:], uint32(timeNow>>28))   // set time_high
-	binary.BigEndian.PutUint16(u[4:], uint16(timeNow>>12))   // set time_mid
-	binary.BigEndian.PutUint16(u[6:], uint16(timeNow&0xfff)) // set time_low (minus four version bits)
+	timeNow, _, err := g.getClockSequence(ctx, V6, atTime, now, 1)
+	if err != nil {
+		return Nil, err
+	}
 
 	// Based on the RFC 9562 recommendation that this data be fully random and not a monotonic counter,
 	//we do NOT support batching version 6 UUIDs.
@@ -353,24 +842,48 @@ func (g *Gen) NewV6AtTime(atTime time.Time) (UUID, error) {
 	if _, err = io.ReadFull(g.rand, u[8:]); err != nil {
 		return Nil, err
 	}
+	encodeV6(&u, timeNow)
+
+	return u, nil
+}
+
+// encodeV6 sets the timestamp, version and variant of u, leaving clock_seq
+// and node as they are.
+func encodeV6(u *UUID, timeNow uint64) {
+	binary.BigEndian.PutUint32(u[0:], uint32(timeNow>>28))   // set time_high
+	binary.BigEndian.PutUint16(u[4:], uint16(timeNow>>12))   // set time_mid
+	binary.BigEndian.PutUint16(u[6:], uint16(timeNow&0xfff)) // set time_low (minus four version bits)
 
 	u.SetVersion(V6)
 
 	//overwrite first 2 bits of byte[8] for the variant
 	u.SetVariant(VariantRFC9562)
-
-	return u, nil
 }
 
 // NewV7 returns a k-sortable UUID based on the current millisecond-precision
 // UNIX epoch and 74 bits of pseudorandom data.
 func (g *Gen) NewV7() (UUID, error) {
-	return g.NewV7AtTime(g.epochFunc())
+	return g.newV7(context.Background(), g.epochFunc(), true)
+}
+
+// NewV7Context is like NewV7, but when the generator uses the MonotonicBlock
+// policy, waiting for the clock to advance stops when ctx is done. In that
+// case the context's error is returned.
+func (g *Gen) NewV7Context(ctx context.Context) (UUID, error) {
+	return g.newV7(ctx, g.epochFunc(), true)
 }
 
 // NewV7 returns a k-sortable UUID based on the provided millisecond-precision
 // UNIX epoch and 74 bits of pseudorandom data.
 func (g *Gen) NewV7AtTime(atTime time.Time) (UUID, error) {
+	return g.newV7(context.Background(), atTime, false)
+}
+
+func (g *Gen) newV7(ctx context.Context, atTime time.Time, now bool) (UUID, error) {
+	if g.stateless {
+		return g.child().newV7(ctx, atTime, now)
+	}
+
 	var u UUID
 	/* https://datatracker.ietf.org/doc/html/rfc9562#name-uuid-version-7
 	    0                   1                   2                   3
@@ -385,38 +898,61 @@ func (g *Gen) NewV7AtTime(atTime time.Time) (UUID, error) {
 	   |                            rand_b                             |
 	   +-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+ */
 
-	ms, clockSeq, err := g.getClockSequence(true, atTime)
-	if err != nil {
+	atTime = g.embeddedTime(atTime)
+	if err := checkTimeRange(V7, atTime); err != nil {
 		return Nil, err
 	}
-	//UUIDv7 features a 48 bit timestamp. First 32bit (4bytes) represents seconds since 1970, followed by 2 bytes for the ms granularity.
-	u[0] = byte(ms >> 40) //1-6 bytes: big-endian unsigned number of Unix epoch timestamp
-	u[1] = byte(ms >> 32)
-	u[2] = byte(ms >> 24)
-	u[3] = byte(ms >> 16)
-	u[4] = byte(ms >> 8)
-	u[5] = byte(ms)
-
-	//Support batching by using a monotonic pseudo-random sequence,
-	//as described in RFC 9562 section 6.2, Method 1.
-	//The 6th byte contains the version and partially rand_a data.
-	//We will lose the most significant bites from the clockSeq (with SetVersion), but it is ok,
-	//we need the least significant that contains the counter to ensure the monotonic property
-	binary.BigEndian.PutUint16(u[6:8], clockSeq) // set rand_a with clock seq which is random and monotonic
 
-	//override first 4bits of u[6].
-	u.SetVersion(V7)
+	var rnd []byte
+	if g.v7Reseeded() {
+		rnd = make([]byte, v7SeedSize)
+		if _, err := io.ReadFull(g.rand, rnd); err != nil {
+			return Nil, err
+		}
+	}
+	var t [1]v7Time
+	if err := g.getV7Time(ctx, atTime, now, rnd, t[:]); err != nil {
+		return Nil, err
+	}
 
 	//set rand_b 64bits of pseudo-random bits (first 2 will be overridden)
-	if _, err = io.ReadFull(g.rand, u[8:16]); err != nil {
+	if _, err := io.ReadFull(g.rand, u[8:16]); err != nil {
 		return Nil, err
 	}
-	//override first 2 bits of byte[8] for the variant
-	u.SetVariant(VariantRFC9562)
+	g.encodeV7(&u, t[0])
 
 	return u, nil
 }
 
+// encodeV7 sets the timestamp, rand_a, version and variant of u from t. The
+// leading bits of rand_b are replaced with the counter, if any, and the
+// other bits of u[8:16] are left as they are.
+func (g *Gen) encodeV7(u *UUID, t v7Time) {
+	//replace the leading bits of rand_b that belong to the counter, if any
+	if n := g.v7RandBBits(); n > 0 {
+		mask := (uint64(1)<<n - 1) << (62 - n)
+		randB := binary.BigEndian.Uint64(u[8:16])
+		binary.BigEndian.PutUint64(u[8:16], randB&^mask|t.randB)
+	}
+	encodeV7Time(u, t.ts)
+}
+
+// encodeV7Time sets the timestamp, rand_a, version and variant of u, leaving
+// rand_b as it is. ts holds unix_ts_ms in its top 48 bits, followed by the
+// 12 bits of rand_a.
+func encodeV7Time(u *UUID, ts uint64) {
+	// By default, rand_a is a monotonic pseudo-random counter, as described
+	// in RFC 9562 section 6.2, Method 1.
+	//UUIDv7 features a 48 bit timestamp, big-endian in bytes 0-5, followed by the version and rand_a.
+	binary.BigEndian.PutUint64(u[0:8], ts>>12<<16|ts&0xfff)
+
+	//override first 4bits of u[6].
+	u.SetVersion(V7)
+
+	//override first 2 bits of byte[8] for the variant
+	u.SetVariant(VariantRFC9562)
+}
+
 // NewV8 returns a UUID based on user-provided data as specified in RFC 9562.
 // See the package-level NewV8 function for documentation.
 func (g *Gen) NewV8(customA []byte, customB []byte, customC []byte) (UUID, error) {
@@ -462,72 +998,565 @@ func (g *Gen) NewV8(customA []byte, customB []byte, customC []byte) (UUID, error
 	return u, nil
 }
 
-// getClockSequence returns the epoch and clock sequence of the provided time,
-// used for generating V1,V6 and V7 UUIDs.
-//
-// When useUnixTSMs is false, it uses the Coordinated Universal Time (UTC) as a count of
-// 100-nanosecond intervals since 00:00:00.00, 15 October 1582 (the date of Gregorian
-// reform to the Christian calendar).
-func (g *Gen) getClockSequence(useUnixTSMs bool, atTime time.Time) (uint64, uint16, error) {
+// initClockSequence initializes the clock sequence, either from the
+// StateStore or randomly, the first time it is called.
+func (g *Gen) initClockSequence() error {
 	var err error
 	g.clockSequenceOnce.Do(func() {
+		if g.loadState() {
+			return
+		}
 		buf := make([]byte, 2)
 		if _, err = io.ReadFull(g.rand, buf); err != nil {
 			return
 		}
 		g.clockSequence = binary.BigEndian.Uint16(buf)
 	})
-	if err != nil {
+	return err
+}
+
+// getClockSequence returns the epoch and clock sequence of the provided time,
+// used for generating V1 and V6 UUIDs.
+//
+// It uses the Coordinated Universal Time (UTC) as a count of 100-nanosecond
+// intervals since 00:00:00.00, 15 October 1582 (the date of Gregorian reform
+// to the Christian calendar).
+//
+// When now is true, atTime was read from the epochFunc, and it is read again
+// if the MonotonicBlock policy needs to wait for the clock.
+//
+// The n-1 timestamps following the returned one are reserved for the caller,
+// and will not be returned by later calls.
+func (g *Gen) getClockSequence(ctx context.Context, version byte, atTime time.Time, now bool, n uint64) (uint64, uint16, error) {
+	if err := g.initClockSequence(); err != nil {
 		return 0, 0, err
 	}
 
+	var reported bool
+	for {
+		timeNow, clockSeq, ev, wait := g.nextClockSequence(version, atTime, n)
+		if ev.Err == nil {
+			return timeNow, clockSeq, nil
+		}
+		if err := g.handleMonotonicEvent(ctx, ev, now, wait, &reported); err != nil {
+			return 0, 0, err
+		}
+		if ev.Policy != MonotonicBlock {
+			return timeNow, clockSeq, nil
+		}
+		atTime = g.embeddedTime(g.epochFunc())
+	}
+}
+
+// nextClockSequence advances the V1/V6 state to atTime. When the clock went
+// backwards, it returns a MonotonicEvent and how long to wait for the clock
+// to catch up; the state is left untouched if the policy rejects the time.
+func (g *Gen) nextClockSequence(version byte, atTime time.Time, n uint64) (uint64, uint16, MonotonicEvent, time.Duration) {
+	timeNow := g.getEpoch(atTime)
+
 	g.storageMutex.Lock()
 	defer g.storageMutex.Unlock()
 
-	var timeNow uint64
-	if useUnixTSMs {
-		timeNow = uint64(atTime.UnixMilli())
-	} else {
-		timeNow = g.getEpoch(atTime)
+	var ev MonotonicEvent
+	rollback := timeNow < g.lastClock
+	if rollback {
+		last, _ := Timestamp(g.lastTime).Time()
+		ev = MonotonicEvent{
+			Err:     ErrClockRollback,
+			Version: version,
+			Policy:  g.monotonicPolicy,
+			Time:    atTime,
+			Last:    last,
+		}
+		switch g.monotonicPolicy {
+		case MonotonicError, MonotonicBlock:
+			return 0, 0, ev, time.Duration(g.lastTime-timeNow+1) * 100
+		}
 	}
+	g.lastClock = timeNow
+
+	// Unless the clock went backwards, the generator is only behind its last
+	// timestamp when it was borrowed or reserved by a previous call.
+	switch {
+	case (g.simulateTicks || g.timestampFuzz > 0) && timeNow <= g.lastTime,
+		timeNow < g.lastTime && (!rollback || g.monotonicPolicy == MonotonicBorrow):
+		timeNow = g.lastTime + 1
+	}
+
 	// Clock didn't change since last UUID generation.
 	// Should increase clock sequence.
 	if timeNow <= g.lastTime {
 		g.clockSequence++
 	}
-	g.lastTime = timeNow
+	g.lastTime = timeNow + n - 1
+
+	g.stateChanged()
 
-	return timeNow, g.clockSequence, nil
+	return timeNow, g.clockSequence, ev, 0
 }
 
-// Returns the hardware address.
-func (g *Gen) getHardwareAddr() ([]byte, error) {
-	var err error
-	g.hardwareAddrOnce.Do(func() {
-		var hwAddr net.HardwareAddr
-		if hwAddr, err = g.hwAddrFunc(); err == nil {
-			copy(g.hardwareAddr[:], hwAddr)
-			return
+// v7Time holds the fields of a V7 UUID that are set by the generator. ts
+// holds unix_ts_ms in its top 48 bits, followed by the 12 bits of rand_a.
+// randB holds the leading bits of rand_b that are part of the counter, as
+// returned by v7RandBBits, in place; its other bits are zero.
+type v7Time struct {
+	ts    uint64
+	randB uint64
+}
+
+// v7SeedSize is the number of random bytes used for each V7 UUID to seed
+// or increment the counter, when v7Reseeded is true.
+const v7SeedSize = 16
+
+// getV7Time fills out with consecutive V7 values for the provided time. rnd
+// holds v7SeedSize random bytes for each value if v7Reseeded is true, and is
+// nil otherwise.
+func (g *Gen) getV7Time(ctx context.Context, atTime time.Time, now bool, rnd []byte, out []v7Time) error {
+	if err := g.initClockSequence(); err != nil {
+		return err
+	}
+
+	var reported bool
+	for {
+		ev, wait := g.nextV7Times(atTime, rnd, out)
+		if ev.Err == nil {
+			return nil
+		}
+		if err := g.handleMonotonicEvent(ctx, ev, now, wait, &reported); err != nil {
+			return err
 		}
+		if ev.Policy != MonotonicBlock {
+			return nil
+		}
+		atTime = g.embeddedTime(g.epochFunc())
+	}
+}
 
+// nextV7Times advances the V7 state to atTime, once for every element of
+// out. When the counter overflowed or the clock went backwards, it returns
+// the first MonotonicEvent and, if the policy rejects the time, how long to
+// wait for the next millisecond; in that case, the state is left untouched.
+//
+// All the values are reserved at once, so they are strictly increasing:
+// MonotonicWrap behaves like MonotonicBorrow when out has several elements,
+// or when the timestamps are fuzzed.
+func (g *Gen) nextV7Times(atTime time.Time, rnd []byte, out []v7Time) (MonotonicEvent, time.Duration) {
+	g.storageMutex.Lock()
+	defer g.storageMutex.Unlock()
+
+	policy := g.monotonicPolicy
+	if (len(out) > 1 || g.timestampFuzz > 0) && policy == MonotonicWrap {
+		policy = MonotonicBorrow
+	}
+
+	last, lastRandB, lastSet, lastClock := g.lastV7, g.lastV7RandB, g.lastV7Set, g.lastV7Clock
+
+	var first MonotonicEvent
+	for i := range out {
+		var seed []byte
+		if rnd != nil {
+			seed = rnd[i*v7SeedSize : (i+1)*v7SeedSize]
+		}
+		ev := g.advanceV7(atTime, seed, policy, &out[i])
+		if ev.Err == nil {
+			continue
+		}
+		switch policy {
+		case MonotonicError, MonotonicBlock:
+			g.lastV7, g.lastV7RandB, g.lastV7Set, g.lastV7Clock = last, lastRandB, lastSet, lastClock
+			return ev, ev.Last.Add(time.Millisecond).Sub(atTime)
+		}
+		if first.Err == nil {
+			first = ev
+		}
+	}
+	g.stateChanged()
+
+	return first, 0
+}
+
+// advanceV7 advances the V7 state to atTime and stores the new value in t,
+// using seed as the source of randomness for seeding and incrementing the
+// counter. It returns a MonotonicEvent when the counter overflowed or the
+// clock went backwards; the caller must hold storageMutex, and restore the
+// state if policy rejects the event.
+//
+// The state is a 122-bit number made of unix_ts_ms, rand_a and the rand_b
+// bits returned by v7RandBBits, which increases with every UUID.
+func (g *Gen) advanceV7(atTime time.Time, seed []byte, policy MonotonicPolicy, t *v7Time) MonotonicEvent {
+	clockMs := uint64(atTime.UnixMilli())
+	lastMs, lastA, lastB := g.lastV7>>12, g.lastV7&0xfff, g.lastV7RandB
+
+	ms := clockMs
+	var randA, randB uint64
+	switch g.v7Method {
+	case v7MethodSubMillisecond:
+		randA = uint64(atTime.Nanosecond()%1e6) << 12 / 1e6
+	case v7MethodCounter, v7MethodMonotonicRandom:
+		randA, randB = g.seedV7(seed)
+	default:
+		// keep the counter running across milliseconds, with its most
+		// significant bit cleared so that every millisecond has room for
+		// at least 2048 UUIDs
+		randA = lastA & 0x7ff
+		if !g.lastV7Set {
+			randA = uint64(g.clockSequence & 0x7ff)
+		}
+	}
+
+	var ev MonotonicEvent
+	advance := ms > lastMs
+	if g.v7Method == v7MethodSubMillisecond {
+		advance = advance || ms == lastMs && randA > lastA
+	}
+	if g.lastV7Set && !advance {
+		randA, randB = lastA, lastB+g.stepV7(seed)
+		if randB >= 1<<62 {
+			randB -= 1 << 62
+			randA++
+		}
+		carry := randA > 0xfff
+		randA &= 0xfff
+
+		switch {
+		case ms < g.lastV7Clock:
+			ev.Err = ErrClockRollback
+		case carry:
+			ev.Err = ErrClockSequenceOverflow
+		}
+		if ev.Err == nil || policy != MonotonicWrap || g.v7Method == v7MethodSubMillisecond {
+			ms = lastMs
+			if carry {
+				ms++
+			}
+		}
+		// Otherwise, keep the historical behavior: the counter wraps
+		// within the millisecond of atTime.
+	}
+
+	if ev.Err != nil {
+		ev.Version = V7
+		ev.Policy = policy
+		ev.Time = atTime
+		ev.Last = time.UnixMilli(int64(lastMs))
+	}
+
+	g.lastV7 = ms<<12 | randA
+	g.lastV7RandB = randB
+	g.lastV7Set = true
+	g.lastV7Clock = clockMs
+
+	t.ts, t.randB = g.lastV7, randB
+	return ev
+}
+
+// v7Reseeded reports whether the V7 counter needs random bytes to be
+// seeded or incremented.
+func (g *Gen) v7Reseeded() bool {
+	return g.v7Method == v7MethodCounter || g.v7Method == v7MethodMonotonicRandom
+}
+
+// seedV7 returns the initial rand_a and rand_b values of the counter for a
+// new millisecond. The most significant bit of the counter is left at zero,
+// so that at least half of its range is available within the millisecond.
+func (g *Gen) seedV7(rnd []byte) (uint64, uint64) {
+	if g.v7Method == v7MethodMonotonicRandom {
+		return uint64(binary.BigEndian.Uint16(rnd[0:2]) & 0x7ff),
+			binary.BigEndian.Uint64(rnd[2:10]) & (1<<62 - 1)
+	}
+
+	n := uint(g.v7CounterBits)
+	c := binary.BigEndian.Uint64(rnd[0:8]) >> (64 - (n - 1))
+	// left-align the counter in the 74 bits of rand_a and rand_b
+	shift := 74 - n
+	return c >> (62 - shift), c << shift & (1<<62 - 1)
+}
+
+// stepV7 returns the amount to add to rand_b to increment the counter.
+func (g *Gen) stepV7(rnd []byte) uint64 {
+	switch g.v7Method {
+	case v7MethodCounter:
+		return 1 << (74 - g.v7CounterBits)
+	case v7MethodMonotonicRandom:
+		return 1 + uint64(binary.BigEndian.Uint32(rnd[12:16]))
+	}
+	return 1 << 62
+}
+
+// v7RandBBits returns how many of the most significant bits of rand_b
+// (excluding the variant) are part of the counter.
+func (g *Gen) v7RandBBits() int {
+	switch g.v7Method {
+	case v7MethodCounter:
+		return g.v7CounterBits - 12
+	case v7MethodMonotonicRandom:
+		return 62
+	}
+	return 0
+}
+
+// loadState initializes lastTime and clockSequence from the StateStore, if
+// one is configured. It reports whether the state was restored.
+func (g *Gen) loadState() bool {
+	if g.stateStore == nil {
+		return false
+	}
+
+	g.storageMutex.Lock()
+	defer g.storageMutex.Unlock()
+
+	s, err := g.stateStore.Load()
+	if err != nil {
+		// RFC 9562 treats unavailable or corrupted state the same way as a
+		// fresh start, but anything other than missing state is reported by
+		// Close.
+		if !errors.Is(err, ErrStateNotFound) {
+			g.stateErr = err
+		}
+		return false
+	}
+	g.lastTime = s.LastTime
+	g.lastClock = s.LastTime
+	g.clockSequence = s.ClockSequence
+	g.lastV7 = s.LastV7
+	g.lastV7RandB = s.LastV7RandB
+	g.lastV7Set = s.LastV7 != 0
+	g.lastV7Clock = s.LastV7 >> 12
+	return true
+}
+
+// stateChanged marks the state as modified and saves it when the save
+// interval has elapsed. The caller must hold storageMutex.
+func (g *Gen) stateChanged() {
+	if g.stateStore == nil {
+		return
+	}
+	g.stateDirty = true
+	if time.Since(g.stateSavedAt) < g.stateSaveInterval {
+		return
+	}
+	if err := g.saveState(); err != nil && g.stateErr == nil {
+		g.stateErr = err
+	}
+}
+
+// saveState writes the current state to the StateStore. The caller must hold
+// storageMutex.
+func (g *Gen) saveState() error {
+	g.stateSavedAt = time.Now()
+	err := g.stateStore.Save(State{
+		LastTime:      g.lastTime,
+		ClockSequence: g.clockSequence,
+		LastV7:        g.lastV7,
+		LastV7RandB:   g.lastV7RandB,
+	})
+	if err == nil {
+		g.stateDirty = false
+	}
+	return err
+}
+
+// nodeRetryInterval is how long a random node is used after the HWAddrFunc
+// failed, before calling it again.
+const nodeRetryInterval = time.Minute
+
+// nodeState is a node of V1 and V2 UUIDs. It is not modified once stored in
+// a generator, so it can be read without locking.
+type nodeState struct {
+	addr [6]byte
+	// gen counts the nodes of the generator, starting at 1.
+	gen uint64
+	// expireAt is when the node is due for rotation, or when a failed
+	// lookup is due for a retry. It is zero when the node never expires.
+	expireAt time.Time
+}
+
+// ResetNode replaces the node of V1 and V2 UUIDs and re-randomizes the clock
+// sequence, as RFC 9562 requires when the node changes. Generators rotating
+// their node with WithNodeRotation pick a new random node; others look the
+// node up again with their HWAddrFunc, such as after the network interfaces
+// changed, and pick a new random node when it fails. The node is shared by
+// the streams of the generator, and their clock sequences are re-randomized
+// the next time they use it.
+func (g *Gen) ResetNode() error {
+	root := g
+	if g.parent != nil {
+		root = g.parent
+	}
+	if _, err := root.updateNode(true); err != nil {
+		return err
+	}
+	var node [6]byte
+	return g.putNode(node[:])
+}
+
+// putNode copies the node of V1 and V2 UUIDs to node, and re-randomizes the
+// clock sequence when the node changed since it was last used.
+func (g *Gen) putNode(node []byte) error {
+	root := g
+	if g.parent != nil {
+		root = g.parent
+	}
+	n := root.node.Load()
+	if n == nil || !n.expireAt.IsZero() && !root.epochFunc().Before(n.expireAt) {
+		var err error
+		if n, err = root.updateNode(false); err != nil {
+			return err
+		}
+	}
+	copy(node, n.addr[:])
+
+	if g.clockSequenceNode.Load() == n.gen {
+		return nil
+	}
+	if err := g.initClockSequence(); err != nil {
+		return err
+	}
+	g.storageMutex.Lock()
+	defer g.storageMutex.Unlock()
+	last := g.clockSequenceNode.Load()
+	if n.gen <= last {
+		return nil
+	}
+	if last != 0 {
+		buf := make([]byte, 2)
+		if _, err := io.ReadFull(g.rand, buf); err != nil {
+			return err
+		}
+		g.clockSequence = binary.BigEndian.Uint16(buf)
+		g.stateChanged()
+	}
+	g.clockSequenceNode.Store(n.gen)
+	return nil
+}
+
+// updateNode replaces the node when the generator has none yet, when it is
+// due for rotation or for a retry of a failed lookup, or when force is true,
+// and returns the current node.
+func (g *Gen) updateNode(force bool) (*nodeState, error) {
+	g.hardwareAddrMutex.Lock()
+	defer g.hardwareAddrMutex.Unlock()
+
+	n := g.node.Load()
+	now := g.epochFunc()
+	if !force && n != nil && (n.expireAt.IsZero() || now.Before(n.expireAt)) {
+		return n, nil
+	}
+
+	next := &nodeState{}
+	if n != nil {
+		next.gen = n.gen
+	}
+	if g.nodeRotation > 0 {
+		if err := g.readRandomNode(&next.addr); err != nil {
+			return nil, err
+		}
+		next.expireAt = now.Add(g.nodeRotation)
+	} else if hwAddr, err := g.hwAddrFunc(); err == nil {
+		copy(next.addr[:], hwAddr)
+	} else if n != nil && !force {
+		// the lookup failed again, keep the random node until the next retry
+		next.addr = n.addr
+		next.expireAt = now.Add(nodeRetryInterval)
+		g.node.Store(next)
+		return next, nil
+	} else {
 		// Initialize hardwareAddr randomly in case
 		// of real network interfaces absence.
-		if _, err = io.ReadFull(g.rand, g.hardwareAddr[:]); err != nil {
-			return
+		if err := g.readRandomNode(&next.addr); err != nil {
+			return nil, err
 		}
-		// Set multicast bit as recommended by RFC-9562
-		g.hardwareAddr[0] |= 0x01
-	})
+		next.expireAt = now.Add(nodeRetryInterval)
+	}
+	next.gen++
+	g.node.Store(next)
+	return next, nil
+}
+
+// readRandomNode reads a random node from the random reader into addr.
+func (g *Gen) readRandomNode(addr *[6]byte) error {
+	if _, err := io.ReadFull(g.rand, addr[:]); err != nil {
+		return err
+	}
+	// Set multicast bit as recommended by RFC-9562
+	addr[0] |= 0x01
+	return nil
+}
+
+// embeddedTime returns the time to embed in a UUID generated at atTime,
+// shifted by the timestamp offset and truncated to the timestamp fuzz.
+func (g *Gen) embeddedTime(atTime time.Time) time.Time {
+	atTime = atTime.Add(g.timestampOffset)
+	if g.timestampFuzz > 0 {
+		atTime = atTime.Truncate(g.timestampFuzz)
+	}
+	return atTime
+}
+
+// TimestampFromV1 returns the Timestamp embedded within a V1 UUID, like the
+// TimestampFromV1 function, minus the offset of the generator, so that V1
+// UUIDs from a generator with the same WithTimestampOffset decode to the
+// time they were generated at. The precision removed by WithTimestampFuzz is
+// not recovered.
+func (g *Gen) TimestampFromV1(u UUID) (Timestamp, error) {
+	ts, err := TimestampFromV1(u)
 	if err != nil {
-		return []byte{}, err
+		return 0, err
+	}
+	return g.trueTimestamp(ts), nil
+}
+
+// TimestampFromV6 is like TimestampFromV1, for V6 UUIDs.
+func (g *Gen) TimestampFromV6(u UUID) (Timestamp, error) {
+	ts, err := TimestampFromV6(u)
+	if err != nil {
+		return 0, err
+	}
+	return g.trueTimestamp(ts), nil
+}
+
+// TimestampFromV7 is like TimestampFromV1, for V7 UUIDs. As V7 UUIDs only
+// hold milliseconds, the result is within a millisecond of the time the UUID
+// was generated at.
+func (g *Gen) TimestampFromV7(u UUID) (Timestamp, error) {
+	ts, err := TimestampFromV7(u)
+	if err != nil {
+		return 0, err
 	}
-	return g.hardwareAddr[:], nil
+	return g.trueTimestamp(ts), nil
+}
+
+// trueTimestamp returns ts minus the timestamp offset.
+func (g *Gen) trueTimestamp(ts Timestamp) Timestamp {
+	return ts - Timestamp(g.timestampOffset/100)
 }
 
 // Returns the difference between UUID epoch (October 15, 1582)
 // and the provided time in 100-nanosecond intervals.
 func (g *Gen) getEpoch(atTime time.Time) uint64 {
-	return epochStart + uint64(atTime.UnixNano()/100)
+	// UnixNano is only defined between the years 1678 and 2262, so the
+	// seconds and the nanoseconds are converted separately.
+	secs := uint64(atTime.Unix() + epochStart/_100nsPerSecond)
+	return secs*_100nsPerSecond + uint64(atTime.Nanosecond()/100)
+}
+
+// checkTimeRange returns an error wrapping ErrTimeOutOfRange when atTime
+// cannot be encoded in a UUID of the provided version.
+func checkTimeRange(version byte, atTime time.Time) error {
+	var minTime, maxTime time.Time
+	switch version {
+	case V1, V2, V6:
+		minTime, maxTime = minTimeV1, maxTimeV1
+	case V7:
+		minTime, maxTime = minTimeV7, maxTimeV7
+	default:
+		return nil
+	}
+	if atTime.Before(minTime) || atTime.After(maxTime) {
+		return fmt.Errorf("%w: %s is not between %s and %s for version %d", ErrTimeOutOfRange,
+			atTime.UTC().Format(time.RFC3339Nano), minTime.Format(time.RFC3339Nano), maxTime.Format(time.RFC3339Nano), version)
+	}
+	return nil
 }
 
 var netInterfaces = net.Interfaces
@@ -28,6 +28,7 @@ import (
 	"errors"
 	"fmt"
 	"net"
+	"os"
 	"strings"
 	"testing"
 	"time"
@@ -35,6 +36,7 @@ import (
 
 func TestGenerator(t *testing.T) {
 	t.Run("NewV1", testNewV1)
+	t.Run("NewV2", testNewV2)
 	t.Run("NewV3", testNewV3)
 	t.Run("NewV4", testNewV4)
 	t.Run("NewV5", testNewV5)
@@ -55,6 +57,132 @@ func testNewV1(t *testing.T) {
 	t.Run("MissingNetworkFaultyRand", testNewV1MissingNetworkFaultyRand)
 	t.Run("MissingNetworkFaultyRandWithOptions", testNewV1MissingNetworkFaultyRandWithOptions)
 	t.Run("AtSpecificTime", testNewV1AtTime)
+	t.Run("SimulatedTicks", testNewV1SimulatedTicks)
+	t.Run("NodeRotation", testNewV1NodeRotation)
+	t.Run("ResetNode", testNewV1ResetNode)
+	t.Run("NodeRetry", testNewV1NodeRetry)
+}
+
+func TestTimestampObfuscation(t *testing.T) {
+	now := time.Date(2024, 1, 2, 3, 4, 5, 678912300, time.UTC)
+	offset := -1000*time.Hour - 123456789*time.Nanosecond
+
+	t.Run("Offset", func(t *testing.T) {
+		g := NewGenWithOptions(
+			WithEpochFunc(func() time.Time { return now }),
+			WithTimestampOffset(offset),
+		)
+		tests := []struct {
+			version byte
+			gen     func() (UUID, error)
+			decode  func(UUID) (Timestamp, error)
+			trueTs  func(UUID) (Timestamp, error)
+			res     time.Duration
+		}{
+			{V1, g.NewV1, TimestampFromV1, g.TimestampFromV1, 100 * time.Nanosecond},
+			{V6, g.NewV6, TimestampFromV6, g.TimestampFromV6, 100 * time.Nanosecond},
+			{V7, g.NewV7, TimestampFromV7, g.TimestampFromV7, time.Millisecond},
+		}
+		for _, tt := range tests {
+			u, err := tt.gen()
+			if err != nil {
+				t.Fatal(err)
+			}
+			ts, err := tt.decode(u)
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := now.Add(offset.Truncate(100)).Truncate(tt.res)
+			if got, _ := ts.Time(); !got.Equal(want) {
+				t.Errorf("V%d embedded time = %v, want %v", tt.version, got, want)
+			}
+			ts, err = tt.trueTs(u)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got, _ := ts.Time(); got.After(now) || now.Sub(got) >= tt.res {
+				t.Errorf("V%d true time = %v, want within %v before %v", tt.version, got, tt.res, now)
+			}
+		}
+
+		if _, err := g.TimestampFromV7(Must(NewV4())); !errors.Is(err, ErrInvalidVersion) {
+			t.Errorf("TimestampFromV7() error = %v, want %v", err, ErrInvalidVersion)
+		}
+
+		g = NewGenWithOptions(WithTimestampOffset(-100 * 365 * 24 * time.Hour))
+		if _, err := g.NewV7(); !errors.Is(err, ErrTimeOutOfRange) {
+			t.Errorf("NewV7() with the time shifted before 1970: error = %v, want %v", err, ErrTimeOutOfRange)
+		}
+	})
+
+	t.Run("Fuzz", func(t *testing.T) {
+		clock := now
+		g := NewGenWithOptions(
+			WithEpochFunc(func() time.Time {
+				clock = clock.Add(time.Microsecond)
+				return clock
+			}),
+			WithTimestampFuzz(time.Hour),
+		)
+		hour := now.Truncate(time.Hour)
+
+		var prev UUID
+		for i := 0; i < 5000; i++ {
+			u, err := g.NewV7()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if bytes.Compare(prev[:], u[:]) >= 0 {
+				t.Fatalf("UUID %v is not greater than %v", u, prev)
+			}
+			prev = u
+			if i == 0 {
+				if ts, _ := TimestampFromV7(u); ts != Timestamp(g.getEpoch(hour)) {
+					t.Errorf("V7 time = %v, want %v", u, hour)
+				}
+			}
+		}
+
+		var prevTs Timestamp
+		for i := 0; i < 100; i++ {
+			for _, fn := range []func() (UUID, error){g.NewV1, g.NewV6} {
+				u, err := fn()
+				if err != nil {
+					t.Fatal(err)
+				}
+				var ts Timestamp
+				if u.Version() == V1 {
+					ts, _ = TimestampFromV1(u)
+				} else {
+					ts, _ = TimestampFromV6(u)
+				}
+				if i == 0 && prevTs == 0 && ts != Timestamp(g.getEpoch(hour)) {
+					t.Errorf("first timestamp %d, want %d", ts, g.getEpoch(hour))
+				}
+				if ts <= prevTs {
+					t.Fatalf("timestamp %d is not greater than %d", ts, prevTs)
+				}
+				prevTs = ts
+			}
+		}
+	})
+
+	t.Run("Stream", func(t *testing.T) {
+		g := NewGenWithOptions(
+			WithEpochFunc(func() time.Time { return now }),
+			WithTimestampOffset(offset),
+			WithTimestampFuzz(time.Second),
+		)
+		for _, s := range []*Gen{g.Stream("s"), g.Stateless()} {
+			u, err := s.NewV7()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if ts, _ := TimestampFromV7(u); ts != Timestamp(g.getEpoch(now.Add(offset).Truncate(time.Second))) {
+				t.Errorf("stream V7 UUID %v does not embed the fuzzed, shifted time", u)
+			}
+		}
+	})
 }
 
 func TestNewGenWithHWAF(t *testing.T) {
@@ -227,6 +355,297 @@ func testNewV1MissingNetworkFaultyRandWithOptions(t *testing.T) {
 	}
 }
 
+func testNewV1SimulatedTicks(t *testing.T) {
+	atTime := time.Date(2020, 1, 2, 3, 4, 5, 6, time.UTC)
+	g := NewGenWithOptions(WithSimulatedTicks())
+
+	var prev Timestamp
+	var clockSeq uint16
+	for i := 0; i < 100; i++ {
+		u, err := g.NewV1AtTime(atTime)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ts, err := TimestampFromV1(u)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if i > 0 && ts != prev+1 {
+			t.Fatalf("timestamp %d of UUID %d, want %d", ts, i, prev+1)
+		}
+		prev = ts
+
+		if seq := binary.BigEndian.Uint16(u[8:]) & 0x3fff; i == 0 {
+			clockSeq = seq
+		} else if seq != clockSeq {
+			t.Fatalf("clock sequence changed from %#04x to %#04x", clockSeq, seq)
+		}
+	}
+
+	// the clock catching up does not reuse the simulated ticks
+	u, err := g.NewV1AtTime(atTime.Add(5 * 100 * time.Nanosecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ts, _ := TimestampFromV1(u); ts != prev+1 {
+		t.Errorf("timestamp %d after the clock caught up, want %d", ts, prev+1)
+	}
+}
+
+// nodeAndClockSequence returns the node and clock sequence of the V1 UUID
+// generated by g at the current time.
+func nodeAndClockSequence(t *testing.T, g *Gen) (net.HardwareAddr, uint16) {
+	t.Helper()
+	u, err := g.NewV1()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return net.HardwareAddr(u[10:]), binary.BigEndian.Uint16(u[8:]) & 0x3fff
+}
+
+func testNewV1NodeRotation(t *testing.T) {
+	now := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	var lookups int
+	g := NewGenWithOptions(
+		WithEpochFunc(func() time.Time { return now }),
+		WithHWAddrFunc(func() (net.HardwareAddr, error) {
+			lookups++
+			return net.HardwareAddr{2, 0, 0, 0, 0, 1}, nil
+		}),
+		WithRandomReader(bytes.NewReader([]byte{2, 2, 2, 2, 2, 2, 0, 1, 4, 4, 4, 4, 4, 4, 0, 2})),
+		WithNodeRotation(time.Hour),
+	)
+
+	node, seq := nodeAndClockSequence(t, g)
+	if want := (net.HardwareAddr{3, 2, 2, 2, 2, 2}); !bytes.Equal(node, want) || seq != 1 {
+		t.Errorf("first UUID: node %v, clock sequence %d, want %v, 1", node, seq, want)
+	}
+
+	now = now.Add(59 * time.Minute)
+	node, seq = nodeAndClockSequence(t, g)
+	if want := (net.HardwareAddr{3, 2, 2, 2, 2, 2}); !bytes.Equal(node, want) || seq != 1 {
+		t.Errorf("before rotation: node %v, clock sequence %d, want %v, 1", node, seq, want)
+	}
+
+	now = now.Add(time.Minute)
+	node, seq = nodeAndClockSequence(t, g)
+	if want := (net.HardwareAddr{5, 4, 4, 4, 4, 4}); !bytes.Equal(node, want) || seq != 2 {
+		t.Errorf("after rotation: node %v, clock sequence %d, want %v, 2", node, seq, want)
+	}
+
+	// rotating nodes are random, so the HWAddrFunc is never used
+	if lookups != 0 {
+		t.Errorf("HWAddrFunc called %d times, want 0", lookups)
+	}
+
+	// running out of randomness for the next node is reported
+	now = now.Add(time.Hour)
+	if _, err := g.NewV1(); err == nil {
+		t.Error("NewV1() with an exhausted random reader did not error")
+	}
+}
+
+func testNewV1ResetNode(t *testing.T) {
+	var lookups byte
+	g := NewGenWithOptions(
+		WithHWAddrFunc(func() (net.HardwareAddr, error) {
+			lookups++
+			return net.HardwareAddr{2, 0, 0, 0, 0, lookups}, nil
+		}),
+		WithRandomReader(bytes.NewReader([]byte{0, 1, 0, 2, 0, 3, 0, 4})),
+	)
+	s := g.Stream("s")
+
+	node, seq := nodeAndClockSequence(t, g)
+	if want := (net.HardwareAddr{2, 0, 0, 0, 0, 1}); !bytes.Equal(node, want) || seq != 1 {
+		t.Errorf("first UUID: node %v, clock sequence %d, want %v, 1", node, seq, want)
+	}
+	if node, _ := nodeAndClockSequence(t, s); !bytes.Equal(node, net.HardwareAddr{2, 0, 0, 0, 0, 1}) {
+		t.Errorf("stream node %v, want the node of the generator", node)
+	}
+
+	if err := g.ResetNode(); err != nil {
+		t.Fatal(err)
+	}
+	node, seq = nodeAndClockSequence(t, g)
+	if want := (net.HardwareAddr{2, 0, 0, 0, 0, 2}); !bytes.Equal(node, want) || seq != 3 {
+		t.Errorf("after ResetNode: node %v, clock sequence %d, want %v, 3", node, seq, want)
+	}
+	// the stream uses the new node with a new clock sequence
+	node, seq = nodeAndClockSequence(t, s)
+	if want := (net.HardwareAddr{2, 0, 0, 0, 0, 2}); !bytes.Equal(node, want) || seq != 4 {
+		t.Errorf("stream after ResetNode: node %v, clock sequence %d, want %v, 4", node, seq, want)
+	}
+
+	// failing to randomize the clock sequence is reported
+	if err := g.ResetNode(); err == nil {
+		t.Error("ResetNode() with an exhausted random reader did not error")
+	}
+}
+
+func testNewV1NodeRetry(t *testing.T) {
+	now := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	var lookups int
+	g := NewGenWithOptions(
+		WithEpochFunc(func() time.Time { return now }),
+		WithHWAddrFunc(func() (net.HardwareAddr, error) {
+			lookups++
+			if lookups < 3 {
+				return nil, ErrNoHwAddressFound
+			}
+			return net.HardwareAddr{0, 1, 2, 3, 4, 5}, nil
+		}),
+		WithRandomReader(bytes.NewReader([]byte{4, 4, 4, 4, 4, 4, 0, 1, 0, 2})),
+	)
+
+	node, seq := nodeAndClockSequence(t, g)
+	if want := (net.HardwareAddr{5, 4, 4, 4, 4, 4}); !bytes.Equal(node, want) || seq != 1 {
+		t.Errorf("first UUID: node %v, clock sequence %d, want %v, 1", node, seq, want)
+	}
+
+	// the random node is kept until a lookup succeeds
+	for _, d := range []time.Duration{30 * time.Second, 31 * time.Second} {
+		now = now.Add(d)
+		node, seq = nodeAndClockSequence(t, g)
+		if want := (net.HardwareAddr{5, 4, 4, 4, 4, 4}); !bytes.Equal(node, want) || seq != 1 {
+			t.Errorf("after %v: node %v, clock sequence %d, want %v, 1", d, node, seq, want)
+		}
+	}
+	if lookups != 2 {
+		t.Errorf("HWAddrFunc called %d times, want 2", lookups)
+	}
+
+	now = now.Add(time.Minute)
+	node, seq = nodeAndClockSequence(t, g)
+	if want := (net.HardwareAddr{0, 1, 2, 3, 4, 5}); !bytes.Equal(node, want) || seq != 2 {
+		t.Errorf("after retry: node %v, clock sequence %d, want %v, 2", node, seq, want)
+	}
+	now = now.Add(time.Hour)
+	if nodeAndClockSequence(t, g); lookups != 3 {
+		t.Errorf("HWAddrFunc called %d times after success, want 3", lookups)
+	}
+}
+
+func testNewV2(t *testing.T) {
+	t.Run("TestVector", testNewV2TestVector)
+	t.Run("Local", testNewV2Local)
+	t.Run("FaultyRand", testNewV2FaultyRand)
+	t.Run("DefaultGeneratorWithoutV2", testNewV2DefaultGeneratorWithoutV2)
+}
+
+func testNewV2TestVector(t *testing.T) {
+	// The vectors were generated by NewDCESecurity of github.com/google/uuid
+	// v1.6.0, an independent implementation of DCE 1.1 Authentication and
+	// Security Services, with its clock set to the time, and its node and
+	// clock sequence set with SetNodeID and SetClockSequence. The first three
+	// use the time, node and clock sequence of the V1 test vector of RFC 9562
+	// appendix A.1, C232AB00-9414-11EC-B3C8-9F6BDECED846: time_low is
+	// replaced by the local ID, and clock_seq_low by the domain.
+	rfcTime := time.Date(2022, 2, 22, 19, 22, 22, 0, time.UTC)
+	rfcNode := net.HardwareAddr{0x9f, 0x6b, 0xde, 0xce, 0xd8, 0x46}
+	tests := []struct {
+		atTime   time.Time
+		node     net.HardwareAddr
+		clockSeq uint16
+		domain   byte
+		id       uint32
+		want     string
+	}{
+		{rfcTime, rfcNode, 0x33c8, DomainPerson, 1000, "000003e8-9414-21ec-b300-9f6bdeced846"},
+		{rfcTime, rfcNode, 0x33c8, DomainGroup, 100, "00000064-9414-21ec-b301-9f6bdeced846"},
+		{rfcTime, rfcNode, 0x33c8, DomainOrg, 0xdeadbeef, "deadbeef-9414-21ec-b302-9f6bdeced846"},
+		{
+			time.Date(2024, 5, 6, 7, 8, 9, 123456700, time.UTC),
+			net.HardwareAddr{0x02, 0x00, 0x5e, 0x10, 0x00, 0x01},
+			0x0a5c, DomainPerson, 501, "000001f5-0b77-21ef-8a00-02005e100001",
+		},
+	}
+	for _, tt := range tests {
+		g := NewGenWithOptions(
+			WithEpochFunc(func() time.Time {
+				return tt.atTime
+			}),
+			WithHWAddrFunc(func() (net.HardwareAddr, error) {
+				return tt.node, nil
+			}),
+		)
+		g.clockSequenceOnce.Do(func() {})
+		g.clockSequence = tt.clockSeq
+
+		u, err := g.NewV2(tt.domain, tt.id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := u.String(); got != tt.want {
+			t.Errorf("NewV2(%d, %d) = %s, want %s", tt.domain, tt.id, got, tt.want)
+		}
+		if got, want := u.Version(), V2; got != want {
+			t.Errorf("generated UUID with version %d, want %d", got, want)
+		}
+		if got, want := u.Variant(), VariantRFC9562; got != want {
+			t.Errorf("generated UUID with variant %d, want %d", got, want)
+		}
+	}
+}
+
+func testNewV2Local(t *testing.T) {
+	if os.Getuid() < 0 {
+		t.Skip("no POSIX IDs on this platform")
+	}
+	for domain, want := range map[byte]int{DomainPerson: os.Getuid(), DomainGroup: os.Getgid()} {
+		u, err := NewV2Local(domain)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if id, _ := IDFromV2(u); int(id) != want {
+			t.Errorf("NewV2Local(%d) has ID %d, want %d", domain, id, want)
+		}
+	}
+	_, err := NewV2Local(DomainOrg)
+	if !errors.Is(err, ErrNoLocalID) {
+		t.Errorf("NewV2Local(DomainOrg) error = %v, want %v", err, ErrNoLocalID)
+	}
+}
+
+func testNewV2FaultyRand(t *testing.T) {
+	g := &Gen{
+		epochFunc:  time.Now,
+		hwAddrFunc: defaultHWAddrFunc,
+		rand: &faultyReader{
+			readToFail: 0, // fail immediately
+		},
+	}
+	u, err := g.NewV2(DomainPerson, 0)
+	testErrCheck(t, "NewV2()", "faulty", err)
+	if u != Nil {
+		t.Errorf("got %v on error, want Nil", u)
+	}
+}
+
+func testNewV2DefaultGeneratorWithoutV2(t *testing.T) {
+	saved := DefaultGenerator
+	defer func() { DefaultGenerator = saved }()
+	// a Generator with only the methods of the interface
+	DefaultGenerator = struct{ Generator }{NewGen()}
+
+	for name, newV2 := range map[string]func() (UUID, error){
+		"NewV2":      func() (UUID, error) { return NewV2(DomainPerson, 1000) },
+		"NewV2Local": func() (UUID, error) { return NewV2Local(DomainPerson) },
+	} {
+		if name == "NewV2Local" && os.Getuid() < 0 {
+			// no POSIX IDs on this platform
+			continue
+		}
+		u, err := newV2()
+		if !errors.Is(err, ErrInvalidVersion) {
+			t.Errorf("%s() error = %v, want %v", name, err, ErrInvalidVersion)
+		}
+		if u != Nil {
+			t.Errorf("%s() = %v on error, want Nil", name, u)
+		}
+	}
+}
+
 func testNewV1AtTime(t *testing.T) {
 	atTime := time.Date(2020, 1, 2, 3, 4, 5, 6, time.UTC)
 
@@ -289,6 +708,9 @@ func testNewV3(t *testing.T) {
 	t.Run("Basic", testNewV3Basic)
 	t.Run("EqualNames", testNewV3EqualNames)
 	t.Run("DifferentNamespaces", testNewV3DifferentNamespaces)
+	t.Run("Bytes", testNewV3Bytes)
+	t.Run("Reader", testNewV3Reader)
+	t.Run("FaultyReader", testNewV3FaultyReader)
 }
 
 func testNewV3Basic(t *testing.T) {
@@ -328,6 +750,40 @@ func testNewV3DifferentNamespaces(t *testing.T) {
 	}
 }
 
+func testNewV3Bytes(t *testing.T) {
+	ns := NamespaceDNS
+	name := "www.example.com"
+	want := NewV3(ns, name)
+	if got := NewV3Bytes(ns, []byte(name)); got != want {
+		t.Errorf("NewV3Bytes(%v, %q) = %v, want %v", ns, name, got, want)
+	}
+	if got, want := NewV3Bytes(ns, nil), NewV3(ns, ""); got != want {
+		t.Errorf("NewV3Bytes(%v, nil) = %v, want %v", ns, got, want)
+	}
+}
+
+func testNewV3Reader(t *testing.T) {
+	ns := NamespaceDNS
+	name := "www.example.com"
+	u, err := NewV3Reader(ns, strings.NewReader(name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := NewV3(ns, name); u != want {
+		t.Errorf("NewV3Reader(%v, %q) = %v, want %v", ns, name, u, want)
+	}
+}
+
+func testNewV3FaultyReader(t *testing.T) {
+	u, err := NewV3Reader(NamespaceDNS, &faultyReader{})
+	if err == nil {
+		t.Errorf("did not error on faulty reader, got %v", u)
+	}
+	if u != Nil {
+		t.Errorf("NewV3Reader with faulty reader = %v, want Nil", u)
+	}
+}
+
 func testNewV4(t *testing.T) {
 	t.Run("Basic", testNewV4Basic)
 	t.Run("DifferentAcrossCalls", testNewV4DifferentAcrossCalls)
@@ -423,6 +879,10 @@ func testNewV5(t *testing.T) {
 	t.Run("Basic", testNewV5Basic)
 	t.Run("EqualNames", testNewV5EqualNames)
 	t.Run("DifferentNamespaces", testNewV5DifferentNamespaces)
+	t.Run("Bytes", testNewV5Bytes)
+	t.Run("Reader", testNewV5Reader)
+	t.Run("FaultyReader", testNewV5FaultyReader)
+	t.Run("DefaultGeneratorWithoutNames", testNewV5DefaultGeneratorWithoutNames)
 }
 
 func testNewV5Basic(t *testing.T) {
@@ -462,6 +922,65 @@ func testNewV5DifferentNamespaces(t *testing.T) {
 	}
 }
 
+func testNewV5Bytes(t *testing.T) {
+	ns := NamespaceDNS
+	name := "www.example.com"
+	want := NewV5(ns, name)
+	if got := NewV5Bytes(ns, []byte(name)); got != want {
+		t.Errorf("NewV5Bytes(%v, %q) = %v, want %v", ns, name, got, want)
+	}
+	if got, want := NewV5Bytes(ns, nil), NewV5(ns, ""); got != want {
+		t.Errorf("NewV5Bytes(%v, nil) = %v, want %v", ns, got, want)
+	}
+}
+
+func testNewV5Reader(t *testing.T) {
+	ns := NamespaceDNS
+	name := "www.example.com"
+	u, err := NewV5Reader(ns, strings.NewReader(name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := NewV5(ns, name); u != want {
+		t.Errorf("NewV5Reader(%v, %q) = %v, want %v", ns, name, u, want)
+	}
+}
+
+func testNewV5FaultyReader(t *testing.T) {
+	u, err := NewV5Reader(NamespaceDNS, &faultyReader{})
+	if err == nil {
+		t.Errorf("did not error on faulty reader, got %v", u)
+	}
+	if u != Nil {
+		t.Errorf("NewV5Reader with faulty reader = %v, want Nil", u)
+	}
+}
+
+func testNewV5DefaultGeneratorWithoutNames(t *testing.T) {
+	saved := DefaultGenerator
+	defer func() { DefaultGenerator = saved }()
+	// a Generator with only the methods of the interface
+	DefaultGenerator = struct{ Generator }{NewGen()}
+
+	ns := NamespaceDNS
+	name := "www.example.com"
+	if got, want := NewV3Bytes(ns, []byte(name)), NewV3(ns, name); got != want {
+		t.Errorf("NewV3Bytes(%v, %q) = %v, want %v", ns, name, got, want)
+	}
+	if got, err := NewV3Reader(ns, strings.NewReader(name)); err != nil || got != NewV3(ns, name) {
+		t.Errorf("NewV3Reader(%v, %q) = %v, %v, want %v", ns, name, got, err, NewV3(ns, name))
+	}
+	if got, want := NewV5Bytes(ns, []byte(name)), NewV5(ns, name); got != want {
+		t.Errorf("NewV5Bytes(%v, %q) = %v, want %v", ns, name, got, want)
+	}
+	if got, err := NewV5Reader(ns, strings.NewReader(name)); err != nil || got != NewV5(ns, name) {
+		t.Errorf("NewV5Reader(%v, %q) = %v, %v, want %v", ns, name, got, err, NewV5(ns, name))
+	}
+	if _, err := NewV5Reader(ns, &faultyReader{}); err == nil {
+		t.Error("NewV5Reader() with a faulty reader did not error")
+	}
+}
+
 func testNewV6(t *testing.T) {
 	t.Run("Basic", testNewV6Basic)
 	t.Run("DifferentAcrossCalls", testNewV6DifferentAcrossCalls)
@@ -473,6 +992,37 @@ func testNewV6(t *testing.T) {
 	t.Run("ShortRandomReadWithOptions", testNewV6ShortRandomReadWithOptions)
 	t.Run("KSortable", testNewV6KSortable)
 	t.Run("AtSpecificTime", testNewV6AtTime)
+	t.Run("SimulatedTicks", testNewV6SimulatedTicks)
+}
+
+func testNewV6SimulatedTicks(t *testing.T) {
+	now := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	g := NewGenWithOptions(
+		WithEpochFunc(func() time.Time { return now }),
+		WithSimulatedTicks(),
+	)
+
+	var prev UUID
+	for i := 0; i < 100; i++ {
+		u, err := g.NewV6()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if bytes.Compare(prev[:8], u[:8]) >= 0 {
+			t.Fatalf("UUID %v timestamp is not greater than %v", u, prev)
+		}
+		prev = u
+	}
+
+	// a clock going backwards borrows the next tick with MonotonicWrap
+	now = now.Add(-time.Second)
+	u, err := g.NewV6()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Compare(prev[:8], u[:8]) >= 0 {
+		t.Errorf("UUID %v timestamp is not greater than %v", u, prev)
+	}
 }
 
 func testNewV6Basic(t *testing.T) {
@@ -710,6 +1260,9 @@ func testNewV7(t *testing.T) {
 	t.Run("KSortable", makeTestNewV7KSortable())
 	t.Run("ClockSequence", makeTestNewV7ClockSequence())
 	t.Run("AtSpecificTime", makeTestNewV7AtTime())
+	t.Run("SubMillisecond", makeTestNewV7SubMillisecond())
+	t.Run("Counter", makeTestNewV7Counter())
+	t.Run("MonotonicRandom", makeTestNewV7MonotonicRandom())
 }
 
 func makeTestNewV7Basic() func(t *testing.T) {
@@ -1004,6 +1557,245 @@ func makeTestNewV7AtTime() func(t *testing.T) {
 	}
 }
 
+func makeTestNewV7SubMillisecond() func(t *testing.T) {
+	return func(t *testing.T) {
+		atTime := time.Date(2022, 2, 22, 19, 22, 22, 500_000, time.UTC)
+		g := NewGenWithOptions(
+			WithV7SubMillisecond(),
+			WithEpochFunc(func() time.Time { return atTime }),
+		)
+
+		u, err := g.NewV7()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, want := u.Version(), V7; got != want {
+			t.Errorf("generated UUID with version %d, want %d", got, want)
+		}
+		if got, want := u.Variant(), VariantRFC9562; got != want {
+			t.Errorf("generated UUID with variant %d, want %d", got, want)
+		}
+		if got, want := u.String()[:19], "017f22e2-79b0-7800-"; got != want {
+			t.Errorf("generated UUID %v, want prefix %s", u, want)
+		}
+		ts, err := TimestampFromV7SubMillisecond(u)
+		if err != nil {
+			t.Fatal(err)
+		}
+		tm, err := ts.Time()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !tm.Equal(atTime) {
+			t.Errorf("extracted time %v, want %v", tm.UTC(), atTime)
+		}
+
+		// Within the same 1/4096 ms, and across a clock going backwards,
+		// the rand_a value keeps increasing.
+		prev := u
+		for i := 0; i < 5000; i++ {
+			if i == 2500 {
+				atTime = atTime.Add(-time.Second)
+			}
+			u, err := g.NewV7()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if bytes.Compare(prev[:8], u[:8]) >= 0 {
+				t.Fatalf("UUID %v is not greater than %v", u, prev)
+			}
+			prev = u
+		}
+	}
+}
+
+// v7Counter returns the value of a counter of the provided number of bits
+// spanning rand_a and rand_b.
+func v7Counter(u UUID, bits int) uint64 {
+	randA := uint64(binary.BigEndian.Uint16(u[6:8]) & 0xfff)
+	randB := binary.BigEndian.Uint64(u[8:16]) & (1<<62 - 1)
+	return randA<<(bits-12) | randB>>(62-(bits-12))
+}
+
+func makeTestNewV7Counter() func(t *testing.T) {
+	return func(t *testing.T) {
+		for _, bits := range []int{12, 26, 42} {
+			t.Run(fmt.Sprint(bits), func(t *testing.T) {
+				atTime := time.UnixMilli(1645557742000)
+				g := NewGenWithOptions(
+					WithV7Counter(bits),
+					WithEpochFunc(func() time.Time { return atTime }),
+				)
+
+				var prev UUID
+				for ms := 0; ms < 3; ms++ {
+					for i := 0; i < 1000; i++ {
+						u, err := g.NewV7()
+						if err != nil {
+							t.Fatal(err)
+						}
+						if got, want := u.Version(), V7; got != want {
+							t.Fatalf("generated UUID with version %d, want %d", got, want)
+						}
+						if got, want := u.Variant(), VariantRFC9562; got != want {
+							t.Fatalf("generated UUID with variant %d, want %d", got, want)
+						}
+						c := v7Counter(u, bits)
+						if i == 0 {
+							if c>>(bits-1) != 0 {
+								t.Errorf("counter %#x reseeded with its most significant bit set", c)
+							}
+						} else if want := v7Counter(prev, bits) + 1; c != want {
+							t.Fatalf("counter = %#x, want %#x", c, want)
+						}
+						if bytes.Compare(prev[:], u[:]) >= 0 {
+							t.Fatalf("UUID %v is not greater than %v", u, prev)
+						}
+						prev = u
+					}
+					atTime = atTime.Add(time.Millisecond)
+				}
+			})
+		}
+
+		t.Run("Clamped", func(t *testing.T) {
+			if got := NewGenWithOptions(WithV7Counter(1)).v7CounterBits; got != 12 {
+				t.Errorf("WithV7Counter(1) uses %d bits, want 12", got)
+			}
+			if got := NewGenWithOptions(WithV7Counter(64)).v7CounterBits; got != 42 {
+				t.Errorf("WithV7Counter(64) uses %d bits, want 42", got)
+			}
+		})
+
+		t.Run("Overflow", func(t *testing.T) {
+			g := NewGenWithOptions(
+				WithV7Counter(12),
+				WithMonotonicPolicy(MonotonicError),
+				WithEpochFunc(func() time.Time { return time.UnixMilli(1645557742000) }),
+			)
+			for i := 0; i < 4096; i++ {
+				_, err := g.NewV7()
+				if errors.Is(err, ErrClockSequenceOverflow) {
+					if i < 2048 {
+						t.Errorf("counter overflowed after %d UUIDs, want at least 2048", i)
+					}
+					return
+				}
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+			t.Error("generated 4096 UUIDs with a 12-bit counter without an error")
+		})
+
+		t.Run("FaultyRand", func(t *testing.T) {
+			g := NewGenWithOptions(
+				WithV7Counter(12),
+				WithRandomReader(&faultyReader{readToFail: 1}),
+			)
+			if u, err := g.NewV7(); err == nil {
+				t.Errorf("got %v, nil error", u)
+			}
+		})
+	}
+}
+
+func makeTestNewV7MonotonicRandom() func(t *testing.T) {
+	return func(t *testing.T) {
+		atTime := time.UnixMilli(1645557742000)
+		g := NewGenWithOptions(
+			WithV7MonotonicRandom(),
+			WithEpochFunc(func() time.Time { return atTime }),
+		)
+
+		var prev UUID
+		for ms := 0; ms < 3; ms++ {
+			for i := 0; i < 1000; i++ {
+				u, err := g.NewV7()
+				if err != nil {
+					t.Fatal(err)
+				}
+				if got, want := u.Version(), V7; got != want {
+					t.Fatalf("generated UUID with version %d, want %d", got, want)
+				}
+				if got, want := u.Variant(), VariantRFC9562; got != want {
+					t.Fatalf("generated UUID with variant %d, want %d", got, want)
+				}
+				if i == 0 && u[6]&0x08 != 0 {
+					t.Errorf("UUID %v reseeded with the most significant bit of rand_a set", u)
+				}
+				if i > 0 {
+					// the 64 low bits grow by at most 2^32 per UUID
+					d := binary.BigEndian.Uint64(u[8:16])&(1<<62-1) - binary.BigEndian.Uint64(prev[8:16])&(1<<62-1)
+					if binary.BigEndian.Uint16(u[6:8]) == binary.BigEndian.Uint16(prev[6:8]) && (d == 0 || d > 1<<32) {
+						t.Fatalf("rand_b increased by %d between %v and %v", d, prev, u)
+					}
+				}
+				if bytes.Compare(prev[:], u[:]) >= 0 {
+					t.Fatalf("UUID %v is not greater than %v", u, prev)
+				}
+				prev = u
+			}
+			atTime = atTime.Add(time.Millisecond)
+		}
+	}
+}
+
+func TestTimeRange(t *testing.T) {
+	g := NewGen()
+	tests := []struct {
+		version  byte
+		new      func(time.Time) (UUID, error)
+		decode   func(UUID) (Timestamp, error)
+		min, max time.Time
+	}{
+		{V1, g.Stateless().NewV1AtTime, TimestampFromV1, MinTimeV1, MaxTimeV1},
+		{V6, g.Stateless().NewV6AtTime, TimestampFromV6, MinTimeV6, MaxTimeV6},
+		{V7, g.Stateless().NewV7AtTime, TimestampFromV7, MinTimeV7, MaxTimeV7},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("V%d", tt.version), func(t *testing.T) {
+			for _, atTime := range []time.Time{tt.min, tt.max, time.Date(3000, 1, 2, 3, 4, 5, 600, time.UTC)} {
+				u, err := tt.new(atTime)
+				if err != nil {
+					t.Fatalf("NewV%dAtTime(%v) unexpected error: %v", tt.version, atTime, err)
+				}
+				ts, err := tt.decode(u)
+				if err != nil {
+					t.Fatal(err)
+				}
+				got, _ := ts.Time()
+				precision := 100 * time.Nanosecond
+				if tt.version == V7 {
+					precision = time.Millisecond
+				}
+				if want := atTime.Truncate(precision); !got.Equal(want) {
+					t.Errorf("NewV%dAtTime(%v) has timestamp %v, want %v", tt.version, atTime, got.UTC(), want)
+				}
+			}
+
+			for _, atTime := range []time.Time{tt.min.Add(-time.Nanosecond), tt.max.Add(time.Nanosecond)} {
+				u, err := tt.new(atTime)
+				if !errors.Is(err, ErrTimeOutOfRange) {
+					t.Errorf("NewV%dAtTime(%v) error = %v, want %v", tt.version, atTime, err, ErrTimeOutOfRange)
+				}
+				if u != Nil {
+					t.Errorf("got %v on error, want Nil", u)
+				}
+			}
+		})
+	}
+
+	t.Run("ExportedBounds", func(t *testing.T) {
+		saved := MinTimeV7
+		MinTimeV7 = time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+		defer func() { MinTimeV7 = saved }()
+		if _, err := g.Stateless().NewV7AtTime(time.Date(1999, 1, 1, 0, 0, 0, 0, time.UTC)); err != nil {
+			t.Errorf("NewV7AtTime() after reassigning MinTimeV7: unexpected error: %v", err)
+		}
+	})
+}
+
 func TestDefaultHWAddrFunc(t *testing.T) {
 	tests := []struct {
 		n  string
@@ -1103,6 +1895,7 @@ func TestDefaultHWAddrFunc(t *testing.T) {
 
 func BenchmarkGenerator(b *testing.B) {
 	b.Run("NewV1", func(b *testing.B) {
+		b.ReportAllocs()
 		for i := 0; i < b.N; i++ {
 			_, _ = NewV1()
 		}
@@ -1112,6 +1905,13 @@ func BenchmarkGenerator(b *testing.B) {
 			_ = NewV3(NamespaceDNS, "www.example.com")
 		}
 	})
+	b.Run("NewV3Bytes", func(b *testing.B) {
+		name := []byte("www.example.com")
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			_ = NewV3Bytes(NamespaceDNS, name)
+		}
+	})
 	b.Run("NewV4", func(b *testing.B) {
 		for i := 0; i < b.N; i++ {
 			_, _ = NewV4()
@@ -1122,6 +1922,13 @@ func BenchmarkGenerator(b *testing.B) {
 			_ = NewV5(NamespaceDNS, "www.example.com")
 		}
 	})
+	b.Run("NewV5Bytes", func(b *testing.B) {
+		name := []byte("www.example.com")
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			_ = NewV5Bytes(NamespaceDNS, name)
+		}
+	})
 	b.Run("NewV6", func(b *testing.B) {
 		for i := 0; i < b.N; i++ {
 			_, _ = NewV6()
@@ -1132,6 +1939,20 @@ func BenchmarkGenerator(b *testing.B) {
 			_, _ = NewV7()
 		}
 	})
+	b.Run("FillV4", func(b *testing.B) {
+		g := NewGen()
+		dst := make([]UUID, 1000)
+		for i := 0; i < b.N; i += len(dst) {
+			_ = g.FillV4(dst)
+		}
+	})
+	b.Run("FillV7", func(b *testing.B) {
+		g := NewGen()
+		dst := make([]UUID, 1000)
+		for i := 0; i < b.N; i += len(dst) {
+			_ = g.FillV7(dst)
+		}
+	})
 }
 
 type faultyReader struct {
@@ -0,0 +1,150 @@
+// Copyright (C) 2013-2018 by Maxim Bublis <b@codemonkey.ru>
+//
+// Permission is hereby granted, free of charge, to any person obtaining
+// a copy of this software and associated documentation files (the
+// "Software"), to deal in the Software without restriction, including
+// without limitation the rights to use, copy, modify, merge, publish,
+// distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so, subject to
+// the following conditions:
+//
+// The above copyright notice and this permission notice shall be
+// included in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+// EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF
+// MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE
+// LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+// OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION
+// WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package uuid
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"hash"
+	"io"
+	"time"
+)
+
+// NewV8Hash returns a name-based V8 UUID, built like a V5 UUID but with the
+// hash function returned by h, as described in RFC 9562 appendix B.2: the
+// namespace UUID and the name are hashed, and the first 16 bytes of the hash
+// become the UUID, with its version and variant set. When h is nil, SHA-256
+// is used.
+//
+// The UUID only depends on the hash function, ns and name, so it stays the
+// same across releases. Returns an error wrapping ErrHashTooShort if the
+// hash produces fewer than 16 bytes.
+func NewV8Hash(h func() hash.Hash, ns UUID, name []byte) (UUID, error) {
+	hh := newHash(h)
+	hh.Write(ns[:])
+	hh.Write(name)
+	return hashToV8(hh)
+}
+
+// NewV8HashReader is like NewV8Hash, but hashes the name read from r until
+// EOF, so large inputs do not need to be held in memory. Returns the error
+// of r, if any.
+func NewV8HashReader(h func() hash.Hash, ns UUID, r io.Reader) (UUID, error) {
+	hh := newHash(h)
+	hh.Write(ns[:])
+	if _, err := io.Copy(hh, r); err != nil {
+		return Nil, err
+	}
+	return hashToV8(hh)
+}
+
+// NewV7Name returns a time-ordered, name-based UUID for the provided time,
+// namespace UUID and name. The UUID has the layout of a V7 UUID: the first 48
+// bits hold the number of milliseconds since the Unix epoch, so UUIDs sort by
+// time. The other bits come from the HMAC-SHA256 of the name keyed with the
+// namespace UUID, so the same time, namespace and name always produce the
+// same UUID, such as when a client retries an event.
+//
+// RFC 9562 requires the rand_a and rand_b fields of V7 UUIDs to be random,
+// so the UUID is a V8 UUID rather than a V7 UUID.
+//
+// Returns an error wrapping ErrTimeOutOfRange if t cannot be encoded in the
+// timestamp of a V7 UUID.
+func NewV7Name(t time.Time, ns UUID, name []byte) (UUID, error) {
+	if err := checkTimeRange(V7, t); err != nil {
+		return Nil, err
+	}
+
+	mac := hmac.New(sha256.New, ns[:])
+	mac.Write(name)
+
+	var u UUID
+	copy(u[:], mac.Sum(nil))
+	// override the first 48 bits with unix_ts_ms
+	binary.BigEndian.PutUint64(u[0:8], uint64(t.UnixMilli())<<16|uint64(binary.BigEndian.Uint16(u[6:8])))
+	u.SetVersion(V8)
+	u.SetVariant(VariantRFC9562)
+
+	return u, nil
+}
+
+// NamespaceHasher generates V5 UUIDs under a single namespace, so the
+// namespace UUID does not need to be passed around with every name. The UUIDs
+// are identical to the ones returned by NewV5, and generating them does not
+// allocate.
+//
+// A NamespaceHasher is not faster than NewV5Bytes: it hashes the namespace
+// for every UUID. The 16-byte namespace never fills a 64-byte SHA-1 block, so
+// a hash state seeded with it could only be cloned, which costs more than
+// hashing the namespace again.
+//
+// A NamespaceHasher is safe for concurrent use.
+type NamespaceHasher struct {
+	ns UUID
+}
+
+// NewNamespaceHasher returns a NamespaceHasher for the namespace UUID ns.
+func NewNamespaceHasher(ns UUID) *NamespaceHasher {
+	return &NamespaceHasher{ns: ns}
+}
+
+// Namespace returns the namespace UUID of the NamespaceHasher.
+func (n *NamespaceHasher) Namespace() UUID {
+	return n.ns
+}
+
+// NewV5 returns a UUID based on the SHA-1 hash of the namespace UUID and
+// name, like NewV5.
+func (n *NamespaceHasher) NewV5(name string) UUID {
+	return newV5Bytes(n.ns, []byte(name))
+}
+
+// NewV5Bytes returns a UUID based on the SHA-1 hash of the namespace UUID and
+// name, like NewV5Bytes.
+func (n *NamespaceHasher) NewV5Bytes(name []byte) UUID {
+	return newV5Bytes(n.ns, name)
+}
+
+// newHash returns a new hash.Hash from h, or a SHA-256 hash when h is nil.
+func newHash(h func() hash.Hash) hash.Hash {
+	if h == nil {
+		return sha256.New()
+	}
+	return h()
+}
+
+// hashToV8 returns the V8 UUID made of the first 16 bytes of the sum of h.
+func hashToV8(h hash.Hash) (UUID, error) {
+	sum := h.Sum(nil)
+	if len(sum) < Size {
+		return Nil, fmt.Errorf("%w: got %d bytes", ErrHashTooShort, len(sum))
+	}
+
+	var u UUID
+	copy(u[:], sum)
+	u.SetVersion(V8)
+	u.SetVariant(VariantRFC9562)
+
+	return u, nil
+}
@@ -0,0 +1,203 @@
+// Copyright (C) 2013-2018 by Maxim Bublis <b@codemonkey.ru>
+//
+// Permission is hereby granted, free of charge, to any person obtaining
+// a copy of this software and associated documentation files (the
+// "Software"), to deal in the Software without restriction, including
+// without limitation the rights to use, copy, modify, merge, publish,
+// distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so, subject to
+// the following conditions:
+//
+// The above copyright notice and this permission notice shall be
+// included in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+// EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF
+// MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE
+// LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+// OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION
+// WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package uuid
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/sha512"
+	"errors"
+	"fmt"
+	"hash"
+	"hash/fnv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestNewV8Hash(t *testing.T) {
+	tests := []struct {
+		name string
+		h    func() hash.Hash
+		ns   UUID
+		in   string
+		want string
+	}{
+		// RFC 9562 appendix B.2
+		{"RFC9562", nil, NamespaceDNS, "www.example.com", "5c146b14-3c52-8afd-938a-375d0df1fbf6"},
+		{"SHA256", sha256.New, NamespaceDNS, "www.example.com", "5c146b14-3c52-8afd-938a-375d0df1fbf6"},
+		{"SHA512", sha512.New, NamespaceDNS, "www.example.com", "94ee4ddb-9f36-8018-9ccf-86a4441691e0"},
+		{"URL", nil, NamespaceURL, "https://www.example.com/", "b31aedee-450a-84de-9880-e238dc547a04"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u, err := NewV8Hash(tt.h, tt.ns, []byte(tt.in))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := u.String(); got != tt.want {
+				t.Errorf("NewV8Hash() = %s, want %s", got, tt.want)
+			}
+			if got, want := u.Version(), V8; got != want {
+				t.Errorf("generated UUID with version %d, want %d", got, want)
+			}
+			if got, want := u.Variant(), VariantRFC9562; got != want {
+				t.Errorf("generated UUID with variant %d, want %d", got, want)
+			}
+
+			ur, err := NewV8HashReader(tt.h, tt.ns, strings.NewReader(tt.in))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if ur != u {
+				t.Errorf("NewV8HashReader() = %v, want %v", ur, u)
+			}
+		})
+	}
+	t.Run("Large", func(t *testing.T) {
+		name := bytes.Repeat([]byte("0123456789abcdef"), 1<<16)
+		u, err := NewV8Hash(nil, NamespaceOID, name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ur, err := NewV8HashReader(nil, NamespaceOID, bytes.NewReader(name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ur != u {
+			t.Errorf("NewV8HashReader() = %v, want %v", ur, u)
+		}
+	})
+	t.Run("ShortHash", func(t *testing.T) {
+		h := func() hash.Hash { return fnv.New64a() }
+		if _, err := NewV8Hash(h, NamespaceDNS, nil); !errors.Is(err, ErrHashTooShort) {
+			t.Errorf("NewV8Hash() error = %v, want %v", err, ErrHashTooShort)
+		}
+		if _, err := NewV8HashReader(h, NamespaceDNS, strings.NewReader("")); !errors.Is(err, ErrHashTooShort) {
+			t.Errorf("NewV8HashReader() error = %v, want %v", err, ErrHashTooShort)
+		}
+	})
+	t.Run("FaultyReader", func(t *testing.T) {
+		u, err := NewV8HashReader(nil, NamespaceDNS, &faultyReader{readToFail: 0})
+		testErrCheck(t, "NewV8HashReader()", "faulty", err)
+		if u != Nil {
+			t.Errorf("got %v on error, want Nil", u)
+		}
+	})
+}
+
+func TestNewV7Name(t *testing.T) {
+	atTime := time.UnixMilli(1645557742000)
+	u, err := NewV7Name(atTime, NamespaceURL, []byte("event-42"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := u.String(), "017f22e2-79b0-8ea5-adce-f516ef266986"; got != want {
+		t.Errorf("NewV7Name() = %s, want %s", got, want)
+	}
+	if got, want := u.Version(), V8; got != want {
+		t.Errorf("generated UUID with version %d, want %d", got, want)
+	}
+	if got, want := u.Variant(), VariantRFC9562; got != want {
+		t.Errorf("generated UUID with variant %d, want %d", got, want)
+	}
+
+	// retries produce the same UUID
+	if u2, err := NewV7Name(atTime, NamespaceURL, []byte("event-42")); err != nil || u2 != u {
+		t.Errorf("NewV7Name() = %v, %v, want %v", u2, err, u)
+	}
+	// other names and namespaces do not
+	if u2, _ := NewV7Name(atTime, NamespaceURL, []byte("event-43")); u2 == u {
+		t.Errorf("NewV7Name() = %v for another name", u2)
+	}
+	if u2, _ := NewV7Name(atTime, NamespaceDNS, []byte("event-42")); u2 == u {
+		t.Errorf("NewV7Name() = %v for another namespace", u2)
+	}
+	// later times sort after
+	u2, err := NewV7Name(atTime.Add(time.Millisecond), NamespaceURL, []byte("event-0"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Compare(u[:], u2[:]) >= 0 {
+		t.Errorf("UUID %v is not greater than %v", u2, u)
+	}
+
+	if _, err := NewV7Name(MinTimeV7.Add(-time.Millisecond), NamespaceURL, nil); !errors.Is(err, ErrTimeOutOfRange) {
+		t.Errorf("NewV7Name() error = %v, want %v", err, ErrTimeOutOfRange)
+	}
+}
+
+func TestNamespaceHasher(t *testing.T) {
+	names := []string{"", "www.example.com", strings.Repeat("long name ", 100)}
+	for _, ns := range []UUID{NamespaceDNS, NamespaceURL, NamespaceOID, NamespaceX500} {
+		n := NewNamespaceHasher(ns)
+		if got := n.Namespace(); got != ns {
+			t.Errorf("Namespace() = %v, want %v", got, ns)
+		}
+		for _, name := range names {
+			want := NewV5(ns, name)
+			if got := n.NewV5(name); got != want {
+				t.Errorf("NewV5(%q) under %v = %v, want %v", name, ns, got, want)
+			}
+			if got := n.NewV5Bytes([]byte(name)); got != want {
+				t.Errorf("NewV5Bytes(%q) under %v = %v, want %v", name, ns, got, want)
+			}
+		}
+	}
+
+	t.Run("Concurrent", func(t *testing.T) {
+		n := NewNamespaceHasher(NamespaceURL)
+		var wg sync.WaitGroup
+		for i := 0; i < 8; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for j := 0; j < 100; j++ {
+					name := []byte(fmt.Sprintf("https://example.com/%d/%d", i, j))
+					if got, want := n.NewV5Bytes(name), NewV5Bytes(NamespaceURL, name); got != want {
+						t.Errorf("NewV5Bytes(%q) = %v, want %v", name, got, want)
+						return
+					}
+				}
+			}()
+		}
+		wg.Wait()
+	})
+}
+
+func BenchmarkNamespaceHasher(b *testing.B) {
+	name := []byte("www.example.com")
+	b.Run("NamespaceHasher", func(b *testing.B) {
+		n := NewNamespaceHasher(NamespaceDNS)
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			_ = n.NewV5Bytes(name)
+		}
+	})
+	b.Run("NewV5Bytes", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			_ = NewV5Bytes(NamespaceDNS, name)
+		}
+	})
+}
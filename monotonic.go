@@ -0,0 +1,152 @@
+// Copyright (C) 2013-2018 by Maxim Bublis <b@codemonkey.ru>
+//
+// Permission is hereby granted, free of charge, to any person obtaining
+// a copy of this software and associated documentation files (the
+// "Software"), to deal in the Software without restriction, including
+// without limitation the rights to use, copy, modify, merge, publish,
+// distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so, subject to
+// the following conditions:
+//
+// The above copyright notice and this permission notice shall be
+// included in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+// EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF
+// MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE
+// LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+// OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION
+// WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package uuid
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// MonotonicPolicy selects what a Gen does when it cannot generate a
+// time-based UUID that sorts after the previous one: either the counter in a
+// V7 UUID is exhausted within a millisecond, or the clock moved backwards.
+type MonotonicPolicy int
+
+const (
+	// MonotonicWrap keeps the historical behavior: the V7 counter wraps
+	// around within the millisecond and a clock going backwards is used as
+	// is, with the clock sequence incremented as RFC 9562 describes for V1.
+	// UUIDs may then sort before previously generated ones. With
+	// WithV7SubMillisecond, it behaves like MonotonicBorrow.
+	MonotonicWrap MonotonicPolicy = iota
+
+	// MonotonicError returns an error wrapping ErrClockSequenceOverflow or
+	// ErrClockRollback, and does not generate a UUID.
+	MonotonicError
+
+	// MonotonicBlock waits until the clock reaches the next tick after the
+	// previous UUID. Only the methods that read the current time can wait:
+	// the NewV*AtTime methods return an error like MonotonicError instead.
+	// Use NewV7Context to bound the wait.
+	MonotonicBlock
+
+	// MonotonicBorrow uses the tick following the previous UUID, so the
+	// embedded timestamp may run ahead of the clock until the clock catches
+	// up.
+	MonotonicBorrow
+)
+
+// String returns the name of the policy.
+func (p MonotonicPolicy) String() string {
+	switch p {
+	case MonotonicWrap:
+		return "wrap"
+	case MonotonicError:
+		return "error"
+	case MonotonicBlock:
+		return "block"
+	case MonotonicBorrow:
+		return "borrow"
+	}
+	return fmt.Sprintf("MonotonicPolicy(%d)", int(p))
+}
+
+// MonotonicEvent describes a counter overflow or a clock rollback detected
+// while generating a time-based UUID. See WithMonotonicHook.
+type MonotonicEvent struct {
+	// Err is ErrClockSequenceOverflow or ErrClockRollback.
+	Err error
+
+	// Version is the version of the UUID being generated.
+	Version byte
+
+	// Policy is the MonotonicPolicy applied to the event.
+	Policy MonotonicPolicy
+
+	// Time is the time the UUID was requested for.
+	Time time.Time
+
+	// Last is the time embedded in the previous UUID of the same version.
+	Last time.Time
+}
+
+// WithMonotonicPolicy is a GenOption that sets the MonotonicPolicy of the
+// generator for V1, V6 and V7 UUIDs. The default is MonotonicWrap.
+//
+// V1 and V6 UUIDs have no counter that can overflow, so the policy only
+// applies to them when the clock moves backwards.
+func WithMonotonicPolicy(p MonotonicPolicy) GenOption {
+	return func(gen *Gen) {
+		gen.monotonicPolicy = p
+	}
+}
+
+// WithMonotonicHook is a GenOption that registers a function called for
+// every MonotonicEvent, whatever the policy. This can be used to raise alerts
+// when the clock goes backwards. The hook is called synchronously, without
+// holding any lock of the generator, and must be safe for concurrent use.
+//
+// When this option is nil, events are not reported.
+func WithMonotonicHook(hook func(MonotonicEvent)) GenOption {
+	return func(gen *Gen) {
+		gen.monotonicHook = hook
+	}
+}
+
+// handleMonotonicEvent reports ev to the hook the first time it is called
+// for a UUID, and applies the policy. For MonotonicBlock, it waits for the
+// provided duration before returning nil, so the caller can retry with the
+// current time.
+func (g *Gen) handleMonotonicEvent(ctx context.Context, ev MonotonicEvent, now bool, wait time.Duration, reported *bool) error {
+	if !*reported {
+		*reported = true
+		if g.monotonicHook != nil {
+			g.monotonicHook(ev)
+		}
+	}
+
+	switch ev.Policy {
+	case MonotonicError:
+	case MonotonicBlock:
+		if now {
+			return sleepContext(ctx, max(wait, time.Microsecond))
+		}
+	default:
+		return nil
+	}
+	return fmt.Errorf("%w: V%d UUID requested at %s, previous at %s", ev.Err, ev.Version,
+		ev.Time.Format(time.RFC3339Nano), ev.Last.Format(time.RFC3339Nano))
+}
+
+// sleepContext pauses the current goroutine for d, or until ctx is done.
+func sleepContext(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
@@ -0,0 +1,348 @@
+// Copyright (C) 2013-2018 by Maxim Bublis <b@codemonkey.ru>
+//
+// Permission is hereby granted, free of charge, to any person obtaining
+// a copy of this software and associated documentation files (the
+// "Software"), to deal in the Software without restriction, including
+// without limitation the rights to use, copy, modify, merge, publish,
+// distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so, subject to
+// the following conditions:
+//
+// The above copyright notice and this permission notice shall be
+// included in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+// EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF
+// MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE
+// LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+// OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION
+// WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package uuid
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// testClock is an EpochFunc whose time is set by the test.
+type testClock struct {
+	mu sync.Mutex
+	t  time.Time
+}
+
+func (c *testClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.t
+}
+
+func (c *testClock) Set(t time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.t = t
+}
+
+func TestMonotonicPolicy(t *testing.T) {
+	t.Run("V7OverflowError", testMonotonicV7OverflowError)
+	t.Run("V7OverflowBorrow", testMonotonicV7OverflowBorrow)
+	t.Run("V7OverflowBlock", testMonotonicV7OverflowBlock)
+	t.Run("V7BlockContext", testMonotonicV7BlockContext)
+	t.Run("V7BlockAtTime", testMonotonicV7BlockAtTime)
+	t.Run("V7RollbackError", testMonotonicV7RollbackError)
+	t.Run("V7RollbackWrap", testMonotonicV7RollbackWrap)
+	t.Run("V6RollbackError", testMonotonicV6RollbackError)
+	t.Run("V6RollbackBorrow", testMonotonicV6RollbackBorrow)
+	t.Run("String", testMonotonicPolicyString)
+}
+
+func testMonotonicV7OverflowError(t *testing.T) {
+	clock := &testClock{t: time.UnixMilli(1645557742000)}
+	g := NewGenWithOptions(
+		WithEpochFunc(clock.Now),
+		WithMonotonicPolicy(MonotonicError),
+	)
+	// the counter is seeded from the clock sequence, with its most
+	// significant bit cleared
+	if err := g.initClockSequence(); err != nil {
+		t.Fatal(err)
+	}
+	g.clockSequence = 0xffff
+
+	var prev UUID
+	for i := 0; i <= 4096; i++ {
+		u, err := g.NewV7()
+		if errors.Is(err, ErrClockSequenceOverflow) {
+			if u != Nil {
+				t.Errorf("got %v on error, want Nil", u)
+			}
+			if i < 2048 {
+				t.Errorf("counter overflowed after %d UUIDs, want at least 2048", i)
+			}
+			return
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if bytes.Compare(prev[:], u[:]) >= 0 {
+			t.Fatalf("UUID %v is not greater than %v", u, prev)
+		}
+		prev = u
+	}
+	t.Fatal("generated 4097 UUIDs in the same millisecond without an error")
+}
+
+func testMonotonicV7OverflowBorrow(t *testing.T) {
+	clock := &testClock{t: time.UnixMilli(1645557742000)}
+	var events []MonotonicEvent
+	g := NewGenWithOptions(
+		WithEpochFunc(clock.Now),
+		WithMonotonicPolicy(MonotonicBorrow),
+		WithMonotonicHook(func(ev MonotonicEvent) {
+			events = append(events, ev)
+		}),
+	)
+
+	var prev UUID
+	for i := 0; i < 10000; i++ {
+		u, err := g.NewV7()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if bytes.Compare(prev[:], u[:]) >= 0 {
+			t.Fatalf("UUID %v is not greater than %v", u, prev)
+		}
+		prev = u
+	}
+
+	ts, err := TimestampFromV7(prev)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tm, _ := ts.Time()
+	if !tm.After(clock.Now()) {
+		t.Errorf("last timestamp %v did not borrow from the next millisecond", tm)
+	}
+	if len(events) < 2 {
+		t.Fatalf("got %d events, want at least 2", len(events))
+	}
+	for _, ev := range events {
+		if !errors.Is(ev.Err, ErrClockSequenceOverflow) || ev.Version != V7 || ev.Policy != MonotonicBorrow {
+			t.Errorf("unexpected event %+v", ev)
+		}
+	}
+}
+
+func testMonotonicV7OverflowBlock(t *testing.T) {
+	start := time.UnixMilli(1645557742000)
+	clock := &testClock{t: start}
+	var events int
+	g := NewGenWithOptions(
+		WithEpochFunc(clock.Now),
+		WithMonotonicPolicy(MonotonicBlock),
+		WithMonotonicHook(func(ev MonotonicEvent) {
+			events++
+			// let time pass while the generator waits
+			clock.Set(ev.Time.Add(time.Millisecond))
+		}),
+	)
+
+	var prev UUID
+	for i := 0; i < 5000; i++ {
+		u, err := g.NewV7()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if bytes.Compare(prev[:], u[:]) >= 0 {
+			t.Fatalf("UUID %v is not greater than %v", u, prev)
+		}
+		prev = u
+	}
+	if events == 0 {
+		t.Fatal("generated 5000 UUIDs in the same millisecond without blocking")
+	}
+	ts, err := TimestampFromV7(prev)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tm, _ := ts.Time()
+	if !tm.Equal(clock.Now()) {
+		t.Errorf("last timestamp %v, want %v", tm, clock.Now())
+	}
+}
+
+func testMonotonicV7BlockContext(t *testing.T) {
+	clock := &testClock{t: time.UnixMilli(1645557742000)}
+	g := NewGenWithOptions(
+		WithEpochFunc(clock.Now),
+		WithMonotonicPolicy(MonotonicBlock),
+	)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	for {
+		_, err := g.NewV7Context(ctx)
+		if err == nil {
+			continue
+		}
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Fatalf("NewV7Context() error = %v, want %v", err, context.DeadlineExceeded)
+		}
+		break
+	}
+}
+
+func testMonotonicV7BlockAtTime(t *testing.T) {
+	atTime := time.UnixMilli(1645557742000)
+	g := NewGenWithOptions(WithMonotonicPolicy(MonotonicBlock))
+	if _, err := g.NewV7AtTime(atTime); err != nil {
+		t.Fatal(err)
+	}
+	_, err := g.NewV7AtTime(atTime.Add(-time.Second))
+	if !errors.Is(err, ErrClockRollback) {
+		t.Errorf("NewV7AtTime() error = %v, want %v", err, ErrClockRollback)
+	}
+}
+
+func testMonotonicV7RollbackError(t *testing.T) {
+	clock := &testClock{t: time.UnixMilli(1645557742000)}
+	g := NewGenWithOptions(
+		WithEpochFunc(clock.Now),
+		WithMonotonicPolicy(MonotonicError),
+	)
+	if _, err := g.NewV7(); err != nil {
+		t.Fatal(err)
+	}
+
+	clock.Set(clock.Now().Add(-time.Second))
+	u, err := g.NewV7()
+	if !errors.Is(err, ErrClockRollback) {
+		t.Fatalf("NewV7() error = %v, want %v", err, ErrClockRollback)
+	}
+	if u != Nil {
+		t.Errorf("got %v on error, want Nil", u)
+	}
+
+	// the generator recovers once the clock catches up
+	clock.Set(clock.Now().Add(2 * time.Second))
+	if _, err := g.NewV7(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func testMonotonicV7RollbackWrap(t *testing.T) {
+	clock := &testClock{t: time.UnixMilli(1645557742000)}
+	var events []MonotonicEvent
+	g := NewGenWithOptions(
+		WithEpochFunc(clock.Now),
+		WithMonotonicHook(func(ev MonotonicEvent) {
+			events = append(events, ev)
+		}),
+	)
+	u1, err := g.NewV7()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	clock.Set(clock.Now().Add(-time.Second))
+	u2, err := g.NewV7()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Compare(u1[:], u2[:]) <= 0 {
+		t.Errorf("UUID %v is not less than %v after the clock went back", u2, u1)
+	}
+	if _, err := g.NewV7(); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(events) != 1 {
+		t.Fatalf("got %d events, want 1", len(events))
+	}
+	ev := events[0]
+	if !errors.Is(ev.Err, ErrClockRollback) || ev.Policy != MonotonicWrap {
+		t.Errorf("unexpected event %+v", ev)
+	}
+	if got, want := ev.Last.Sub(ev.Time), time.Second; got != want {
+		t.Errorf("event reports a rollback of %v, want %v", got, want)
+	}
+}
+
+func testMonotonicV6RollbackError(t *testing.T) {
+	clock := &testClock{t: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)}
+	g := NewGenWithOptions(
+		WithEpochFunc(clock.Now),
+		WithMonotonicPolicy(MonotonicError),
+	)
+	if _, err := g.NewV6(); err != nil {
+		t.Fatal(err)
+	}
+	// the same tick is not an error for V6
+	if _, err := g.NewV6(); err != nil {
+		t.Fatal(err)
+	}
+
+	clock.Set(clock.Now().Add(-time.Microsecond))
+	_, err := g.NewV6()
+	if !errors.Is(err, ErrClockRollback) {
+		t.Fatalf("NewV6() error = %v, want %v", err, ErrClockRollback)
+	}
+	_, err = g.NewV1()
+	if !errors.Is(err, ErrClockRollback) {
+		t.Fatalf("NewV1() error = %v, want %v", err, ErrClockRollback)
+	}
+}
+
+func testMonotonicV6RollbackBorrow(t *testing.T) {
+	clock := &testClock{t: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)}
+	var events int
+	g := NewGenWithOptions(
+		WithEpochFunc(clock.Now),
+		WithMonotonicPolicy(MonotonicBorrow),
+		WithMonotonicHook(func(MonotonicEvent) {
+			events++
+		}),
+	)
+	prev, err := g.NewV6()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	clock.Set(clock.Now().Add(-time.Second))
+	for i := 0; i < 10; i++ {
+		u, err := g.NewV6()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if bytes.Compare(prev[:8], u[:8]) >= 0 {
+			t.Fatalf("UUID %v timestamp is not greater than %v", u, prev)
+		}
+		prev = u
+	}
+	if events != 1 {
+		t.Errorf("got %d events, want 1", events)
+	}
+}
+
+func testMonotonicPolicyString(t *testing.T) {
+	tests := []struct {
+		p    MonotonicPolicy
+		want string
+	}{
+		{MonotonicWrap, "wrap"},
+		{MonotonicError, "error"},
+		{MonotonicBlock, "block"},
+		{MonotonicBorrow, "borrow"},
+		{MonotonicPolicy(42), "MonotonicPolicy(42)"},
+	}
+	for _, tt := range tests {
+		if got := tt.p.String(); got != tt.want {
+			t.Errorf("MonotonicPolicy(%d).String() = %q, want %q", int(tt.p), got, tt.want)
+		}
+	}
+}
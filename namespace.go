@@ -0,0 +1,344 @@
+// Copyright (C) 2013-2018 by Maxim Bublis <b@codemonkey.ru>
+//
+// Permission is hereby granted, free of charge, to any person obtaining
+// a copy of this software and associated documentation files (the
+// "Software"), to deal in the Software without restriction, including
+// without limitation the rights to use, copy, modify, merge, publish,
+// distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so, subject to
+// the following conditions:
+//
+// The above copyright notice and this permission notice shall be
+// included in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+// EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF
+// MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE
+// LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+// OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION
+// WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package uuid
+
+import (
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"fmt"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+// NewV5Path returns the V5 UUID of a path of names under the namespace UUID
+// ns, such as an organization, a project and an environment. Each segment
+// is hashed under the UUID of the previous ones, from the first segment to
+// the last:
+//
+//	NewV5Path(ns, a, b, c) == NewV5(NewV5(NewV5(ns, a), b), c)
+//
+// With no segments, it returns ns.
+func NewV5Path(ns UUID, segments ...string) UUID {
+	for _, name := range segments {
+		ns = NewV5(ns, name)
+	}
+	return ns
+}
+
+// Namespace is a namespace UUID for V5 UUIDs in a hierarchy of names. The
+// UUIDs of its children are derived like in NewV5Path, and cached so that
+// deriving them again does not hash their path again.
+//
+// The cache of a Namespace grows with each distinct child name. For names
+// with many distinct values, such as the last segment of a path, use NewV5
+// instead of Child.
+//
+// The zero Namespace is the Nil namespace, without a cache. A Namespace is
+// safe for concurrent use.
+type Namespace struct {
+	n *namespaceNode
+}
+
+// namespaceNode is the UUID and cached children of a Namespace.
+type namespaceNode struct {
+	id       UUID
+	children sync.Map // string to *namespaceNode
+}
+
+// NewNamespace returns the Namespace of the namespace UUID ns.
+func NewNamespace(ns UUID) Namespace {
+	return Namespace{n: &namespaceNode{id: ns}}
+}
+
+// UUID returns the namespace UUID.
+func (ns Namespace) UUID() UUID {
+	if ns.n == nil {
+		return Nil
+	}
+	return ns.n.id
+}
+
+// String returns the canonical string representation of the namespace UUID.
+func (ns Namespace) String() string {
+	return ns.UUID().String()
+}
+
+// Child returns the Namespace of the V5 UUID of name under ns.
+func (ns Namespace) Child(name string) Namespace {
+	if ns.n == nil {
+		return NewNamespace(NewV5(Nil, name))
+	}
+	if c, ok := ns.n.children.Load(name); ok {
+		return Namespace{n: c.(*namespaceNode)}
+	}
+	c, _ := ns.n.children.LoadOrStore(name, &namespaceNode{id: NewV5(ns.n.id, name)})
+	return Namespace{n: c.(*namespaceNode)}
+}
+
+// Path returns the Namespace of the path of names under ns, like calling
+// Child for each segment in order.
+func (ns Namespace) Path(segments ...string) Namespace {
+	for _, name := range segments {
+		ns = ns.Child(name)
+	}
+	return ns
+}
+
+// NewV5 returns the V5 UUID of name under ns, like
+// ns.Child(name).UUID(), without caching it.
+func (ns Namespace) NewV5(name string) UUID {
+	return NewV5(ns.UUID(), name)
+}
+
+// NewV3DNS returns a V3 UUID for the domain name under NamespaceDNS. The
+// name is normalized like in NewV5DNS.
+func NewV3DNS(name string) UUID {
+	return NewV3(NamespaceDNS, normalizeDNS(name))
+}
+
+// NewV5DNS returns a V5 UUID for the domain name under NamespaceDNS. Domain
+// names are case-insensitive and may be fully qualified, so the name is
+// lowercased and a trailing dot is removed before hashing: "Example.com."
+// and "example.com" produce the same UUID.
+func NewV5DNS(name string) UUID {
+	return NewV5(NamespaceDNS, normalizeDNS(name))
+}
+
+// NewV3URL returns a V3 UUID for the URL under NamespaceURL. The URL is
+// normalized like in NewV5URL.
+func NewV3URL(u *url.URL) UUID {
+	return NewV3(NamespaceURL, normalizeURL(u))
+}
+
+// NewV5URL returns a V5 UUID for the URL under NamespaceURL. The URL is
+// normalized as described in RFC 3986 section 6.2 before hashing: the scheme
+// and host are lowercased, percent-encodings are uppercased, unreserved
+// characters are decoded, dot segments are removed from the path, an empty
+// path becomes "/", and an empty port or the default port of the http, https,
+// ws, wss and ftp schemes is removed. "HTTP://Example.com:80/a/../%7eb" and
+// "http://example.com/~b" produce the same UUID.
+func NewV5URL(u *url.URL) UUID {
+	return NewV5(NamespaceURL, normalizeURL(u))
+}
+
+// NewV3OID returns a V3 UUID for the object identifier under NamespaceOID.
+// The name is the DER encoding of oid, like in NewV5OID.
+func NewV3OID(oid asn1.ObjectIdentifier) (UUID, error) {
+	der, err := marshalOID(oid)
+	if err != nil {
+		return Nil, err
+	}
+	return NewV3Bytes(NamespaceOID, der), nil
+}
+
+// NewV5OID returns a V5 UUID for the object identifier under NamespaceOID.
+// The name is the DER encoding of oid. Returns an error if oid cannot be
+// encoded, such as when it has fewer than two components.
+func NewV5OID(oid asn1.ObjectIdentifier) (UUID, error) {
+	der, err := marshalOID(oid)
+	if err != nil {
+		return Nil, err
+	}
+	return NewV5Bytes(NamespaceOID, der), nil
+}
+
+// NewV3X500 returns a V3 UUID for the distinguished name under
+// NamespaceX500. The name is the DER encoding of name, like in NewV5X500.
+func NewV3X500(name pkix.Name) (UUID, error) {
+	der, err := marshalX500(name)
+	if err != nil {
+		return Nil, err
+	}
+	return NewV3Bytes(NamespaceX500, der), nil
+}
+
+// NewV5X500 returns a V5 UUID for the distinguished name under
+// NamespaceX500. The name is the DER encoding of the RDNSequence of name, as
+// in X.509 certificates, so equal names produce the same UUID regardless of
+// how they were written. Returns an error if name cannot be encoded.
+func NewV5X500(name pkix.Name) (UUID, error) {
+	der, err := marshalX500(name)
+	if err != nil {
+		return Nil, err
+	}
+	return NewV5Bytes(NamespaceX500, der), nil
+}
+
+// normalizeDNS returns the lowercase domain name without its trailing dot.
+func normalizeDNS(name string) string {
+	return strings.ToLower(strings.TrimSuffix(name, "."))
+}
+
+// defaultPorts are the ports removed from the URLs of their scheme by
+// normalizeURL.
+var defaultPorts = map[string]string{
+	"ftp":   "21",
+	"http":  "80",
+	"https": "443",
+	"ws":    "80",
+	"wss":   "443",
+}
+
+// normalizeURL returns the string form of u after the syntax-based and
+// scheme-based normalizations of RFC 3986 section 6.2.
+func normalizeURL(u *url.URL) string {
+	scheme := strings.ToLower(u.Scheme)
+
+	var b strings.Builder
+	if scheme != "" {
+		b.WriteString(scheme)
+		b.WriteByte(':')
+	}
+	if u.Opaque != "" {
+		b.WriteString(normalizePercent(u.Opaque))
+	} else {
+		host := strings.ToLower(u.Host)
+		if port := u.Port(); port == "" || port == defaultPorts[scheme] {
+			// remove an empty or default port, with its ':'
+			host = strings.TrimSuffix(host, ":"+port)
+		}
+		if host != "" || u.User != nil {
+			b.WriteString("//")
+			if u.User != nil {
+				b.WriteString(u.User.String())
+				b.WriteByte('@')
+			}
+			b.WriteString(host)
+		}
+
+		path := removeDotSegments(normalizePercent(u.EscapedPath()))
+		if path == "" && host != "" {
+			path = "/"
+		}
+		b.WriteString(path)
+	}
+	if u.ForceQuery || u.RawQuery != "" {
+		b.WriteByte('?')
+		b.WriteString(normalizePercent(u.RawQuery))
+	}
+	if u.Fragment != "" {
+		b.WriteByte('#')
+		b.WriteString(normalizePercent(u.EscapedFragment()))
+	}
+	return b.String()
+}
+
+// normalizePercent decodes the percent-encoded unreserved characters of s,
+// and uppercases the hexadecimal digits of the other percent-encodings.
+func normalizePercent(s string) string {
+	if !strings.Contains(s, "%") {
+		return s
+	}
+	const upperhex = "0123456789ABCDEF"
+
+	var b strings.Builder
+	b.Grow(len(s))
+	for i := 0; i < len(s); i++ {
+		if s[i] != '%' || i+2 >= len(s) {
+			b.WriteByte(s[i])
+			continue
+		}
+		hi, lo := fromHexChar(s[i+1]), fromHexChar(s[i+2])
+		if hi == 255 || lo == 255 {
+			b.WriteByte(s[i])
+			continue
+		}
+		c := hi<<4 | lo
+		if isUnreserved(c) {
+			b.WriteByte(c)
+		} else {
+			b.Write([]byte{'%', upperhex[c>>4], upperhex[c&0xf]})
+		}
+		i += 2
+	}
+	return b.String()
+}
+
+// isUnreserved reports whether c is an unreserved character of RFC 3986
+// section 2.3.
+func isUnreserved(c byte) bool {
+	switch {
+	case 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z', '0' <= c && c <= '9':
+		return true
+	}
+	return c == '-' || c == '.' || c == '_' || c == '~'
+}
+
+// removeDotSegments removes the "." and ".." segments of path, as described
+// in RFC 3986 section 5.2.4. Unlike path.Clean, it keeps empty segments and
+// trailing slashes.
+func removeDotSegments(path string) string {
+	var out []string
+	in := path
+	for in != "" {
+		switch {
+		case strings.HasPrefix(in, "../"):
+			in = in[3:]
+		case strings.HasPrefix(in, "./"):
+			in = in[2:]
+		case strings.HasPrefix(in, "/./"):
+			in = in[2:]
+		case in == "/.":
+			in = "/"
+		case strings.HasPrefix(in, "/../"):
+			in = in[3:]
+			if len(out) > 0 {
+				out = out[:len(out)-1]
+			}
+		case in == "/..":
+			in = "/"
+			if len(out) > 0 {
+				out = out[:len(out)-1]
+			}
+		case in == "." || in == "..":
+			in = ""
+		default:
+			// move the first segment, with its leading slash, to the output
+			i := strings.IndexByte(in[1:], '/') + 1
+			if i == 0 {
+				i = len(in)
+			}
+			out = append(out, in[:i])
+			in = in[i:]
+		}
+	}
+	return strings.Join(out, "")
+}
+
+// marshalOID returns the DER encoding of oid.
+func marshalOID(oid asn1.ObjectIdentifier) ([]byte, error) {
+	der, err := asn1.Marshal(oid)
+	if err != nil {
+		return nil, fmt.Errorf("uuid: cannot encode OID %v: %w", oid, err)
+	}
+	return der, nil
+}
+
+// marshalX500 returns the DER encoding of the RDNSequence of name.
+func marshalX500(name pkix.Name) ([]byte, error) {
+	der, err := asn1.Marshal(name.ToRDNSequence())
+	if err != nil {
+		return nil, fmt.Errorf("uuid: cannot encode X.500 name %v: %w", name, err)
+	}
+	return der, nil
+}
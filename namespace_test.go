@@ -0,0 +1,237 @@
+// Copyright (C) 2013-2018 by Maxim Bublis <b@codemonkey.ru>
+//
+// Permission is hereby granted, free of charge, to any person obtaining
+// a copy of this software and associated documentation files (the
+// "Software"), to deal in the Software without restriction, including
+// without limitation the rights to use, copy, modify, merge, publish,
+// distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so, subject to
+// the following conditions:
+//
+// The above copyright notice and this permission notice shall be
+// included in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+// EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF
+// MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE
+// LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+// OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION
+// WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package uuid
+
+import (
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"net/url"
+	"sync"
+	"testing"
+)
+
+func TestNewV5Path(t *testing.T) {
+	want := NewV5(NewV5(NewV5(NamespaceURL, "org"), "project"), "env")
+	if got := NewV5Path(NamespaceURL, "org", "project", "env"); got != want {
+		t.Errorf("NewV5Path() = %s, want %s", got, want)
+	}
+	if got := NewV5Path(NamespaceURL, "env", "project", "org"); got == want {
+		t.Errorf("NewV5Path() = %s for the reversed path", got)
+	}
+	if got := NewV5Path(NamespaceURL); got != NamespaceURL {
+		t.Errorf("NewV5Path() with no segments = %s, want %s", got, NamespaceURL)
+	}
+}
+
+func TestNamespace(t *testing.T) {
+	root := NewNamespace(NamespaceURL)
+	if got := root.UUID(); got != NamespaceURL {
+		t.Errorf("UUID() = %s, want %s", got, NamespaceURL)
+	}
+	if got, want := root.String(), NamespaceURL.String(); got != want {
+		t.Errorf("String() = %s, want %s", got, want)
+	}
+
+	want := NewV5Path(NamespaceURL, "org", "project", "env")
+	env := root.Child("org").Child("project").Child("env")
+	if got := env.UUID(); got != want {
+		t.Errorf("Child() = %s, want %s", got, want)
+	}
+	if got := root.Path("org", "project", "env").UUID(); got != want {
+		t.Errorf("Path() = %s, want %s", got, want)
+	}
+	if got, want := env.NewV5("secret"), NewV5Path(NamespaceURL, "org", "project", "env", "secret"); got != want {
+		t.Errorf("NewV5() = %s, want %s", got, want)
+	}
+	if got := root.Path(); got != root {
+		t.Errorf("Path() with no segments = %v, want %v", got, root)
+	}
+
+	// children are cached
+	if root.Path("org", "project", "env") != env {
+		t.Error("Path() did not return the cached Namespace")
+	}
+	if _, ok := env.n.children.Load("secret"); ok {
+		t.Error("NewV5() cached its result")
+	}
+
+	var zero Namespace
+	if got := zero.UUID(); got != Nil {
+		t.Errorf("zero UUID() = %s, want %s", got, Nil)
+	}
+	if got, want := zero.Child("a").UUID(), NewV5(Nil, "a"); got != want {
+		t.Errorf("zero Child() = %s, want %s", got, want)
+	}
+
+	t.Run("Concurrent", func(t *testing.T) {
+		root := NewNamespace(NamespaceDNS)
+		got := make([]Namespace, 8)
+		var wg sync.WaitGroup
+		for i := range got {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				got[i] = root.Path("a", "b")
+			}()
+		}
+		wg.Wait()
+		for _, ns := range got {
+			if ns != got[0] {
+				t.Fatalf("Path() returned %v and %v", ns, got[0])
+			}
+		}
+	})
+}
+
+func TestNewV5DNS(t *testing.T) {
+	want := "cfbff0d1-9375-5685-968c-48ce8b15ae17"
+	for _, name := range []string{"example.com", "Example.com.", "EXAMPLE.COM"} {
+		if got := NewV5DNS(name).String(); got != want {
+			t.Errorf("NewV5DNS(%q) = %s, want %s", name, got, want)
+		}
+		if got, want := NewV3DNS(name), NewV3(NamespaceDNS, "example.com"); got != want {
+			t.Errorf("NewV3DNS(%q) = %s, want %s", name, got, want)
+		}
+	}
+	if u1, u2 := NewV5DNS("example.com"), NewV5DNS("example.org"); u1 == u2 {
+		t.Errorf("NewV5DNS() = %s for different names", u1)
+	}
+}
+
+func TestNewV5URL(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{in: "http://example.com/~b", want: "http://example.com/~b"},
+		{in: "HTTP://Example.COM:80/a/../%7eb", want: "http://example.com/~b"},
+		{in: "http://example.com", want: "http://example.com/"},
+		{in: "https://example.com:443/", want: "https://example.com/"},
+		{in: "https://example.com:8443/", want: "https://example.com:8443/"},
+		{in: "http://example.com:443/", want: "http://example.com:443/"},
+		{in: "http://example.com:/", want: "http://example.com/"},
+		{in: "http://example.com:", want: "http://example.com/"},
+		{in: "http://[::1]:/", want: "http://[::1]/"},
+		{in: "http://[::1]:80/", want: "http://[::1]/"},
+		{in: "http://example.com/a/./b/../../c/", want: "http://example.com/c/"},
+		{in: "http://example.com/a//b/..", want: "http://example.com/a//"},
+		{in: "http://example.com/%2e%2E/a", want: "http://example.com/a"},
+		{in: "http://example.com/a%2fb%3A", want: "http://example.com/a%2Fb%3A"},
+		{in: "http://example.com/?q=%7e%2f#Frag%2d", want: "http://example.com/?q=~%2F#Frag-"},
+		{in: "http://example.com/?", want: "http://example.com/?"},
+		{in: "http://User@Example.com/", want: "http://User@example.com/"},
+		{in: "MAILTO:user@Example.com", want: "mailto:user@Example.com"},
+		{in: "a/./b/../c", want: "a/c"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			u, err := url.Parse(tt.in)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := normalizeURL(u); got != tt.want {
+				t.Errorf("normalizeURL(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+			if got, want := NewV5URL(u), NewV5(NamespaceURL, tt.want); got != want {
+				t.Errorf("NewV5URL(%q) = %s, want %s", tt.in, got, want)
+			}
+			if got, want := NewV3URL(u), NewV3(NamespaceURL, tt.want); got != want {
+				t.Errorf("NewV3URL(%q) = %s, want %s", tt.in, got, want)
+			}
+		})
+	}
+
+	u, _ := url.Parse("HTTP://Example.COM:80/a/../%7eb")
+	if got, want := NewV5URL(u).String(), "d518c525-5fd7-5a4d-9202-f0da256d73f8"; got != want {
+		t.Errorf("NewV5URL(%s) = %s, want %s", u, got, want)
+	}
+}
+
+func TestNewV5OID(t *testing.T) {
+	oid := asn1.ObjectIdentifier{1, 3, 6, 1}
+	u, err := NewV5OID(oid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := u.String(), "9b471a51-d477-5f08-b929-e757489a780d"; got != want {
+		t.Errorf("NewV5OID(%v) = %s, want %s", oid, got, want)
+	}
+	u, err = NewV3OID(oid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := u.String(), "fd4ca762-f19f-33a2-a232-eefdba1ced0f"; got != want {
+		t.Errorf("NewV3OID(%v) = %s, want %s", oid, got, want)
+	}
+
+	for _, oid := range []asn1.ObjectIdentifier{nil, {1}, {3, 1}} {
+		if u, err := NewV5OID(oid); err == nil {
+			t.Errorf("NewV5OID(%v) = %s, want error", oid, u)
+		}
+		if u, err := NewV3OID(oid); err == nil {
+			t.Errorf("NewV3OID(%v) = %s, want error", oid, u)
+		}
+	}
+}
+
+func TestNewV5X500(t *testing.T) {
+	name := pkix.Name{
+		Country:      []string{"US"},
+		Organization: []string{"Example"},
+		CommonName:   "www.example.com",
+	}
+	der, err := asn1.Marshal(name.ToRDNSequence())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	u, err := NewV5X500(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := NewV5Bytes(NamespaceX500, der); u != want {
+		t.Errorf("NewV5X500(%v) = %s, want %s", name, u, want)
+	}
+	u, err = NewV3X500(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := NewV3Bytes(NamespaceX500, der); u != want {
+		t.Errorf("NewV3X500(%v) = %s, want %s", name, u, want)
+	}
+
+	// the same name parsed back from its DER encoding has the same UUID
+	var rdn pkix.RDNSequence
+	if _, err := asn1.Unmarshal(der, &rdn); err != nil {
+		t.Fatal(err)
+	}
+	var parsed pkix.Name
+	parsed.FillFromRDNSequence(&rdn)
+	if u2, err := NewV3X500(parsed); err != nil || u2 != u {
+		t.Errorf("NewV3X500(%v) = %s, %v, want %s", parsed, u2, err, u)
+	}
+
+	bad := pkix.Name{ExtraNames: []pkix.AttributeTypeAndValue{{Type: asn1.ObjectIdentifier{1}, Value: "x"}}}
+	if u, err := NewV5X500(bad); err == nil {
+		t.Errorf("NewV5X500(%v) = %s, want error", bad, u)
+	}
+}
@@ -0,0 +1,350 @@
+// Copyright (C) 2013-2018 by Maxim Bublis <b@codemonkey.ru>
+//
+// Permission is hereby granted, free of charge, to any person obtaining
+// a copy of this software and associated documentation files (the
+// "Software"), to deal in the Software without restriction, including
+// without limitation the rights to use, copy, modify, merge, publish,
+// distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so, subject to
+// the following conditions:
+//
+// The above copyright notice and this permission notice shall be
+// included in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+// EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF
+// MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE
+// LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+// OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION
+// WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package uuid
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// The files and hostname read by the HWAddrFunc providers, as variables for
+// the tests.
+var (
+	machineIDPaths = []string{"/etc/machine-id", "/var/lib/dbus/machine-id"}
+	cgroupPath     = "/proc/self/cgroup"
+	mountinfoPath  = "/proc/self/mountinfo"
+	osHostname     = os.Hostname
+)
+
+// MachineIDHWAddrFunc returns an HWAddrFunc providing a node derived from
+// the machine ID of systemd or D-Bus, read from /etc/machine-id or
+// /var/lib/dbus/machine-id. The node is stable across restarts of the
+// machine, and the machine ID cannot be recovered from it.
+//
+// As with every HWAddrFunc, when the machine ID cannot be read, the
+// generator falls back to a random node.
+func MachineIDHWAddrFunc() HWAddrFunc {
+	return func() (net.HardwareAddr, error) {
+		var errs []error
+		for _, path := range machineIDPaths {
+			b, err := os.ReadFile(path)
+			if err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			if id := strings.TrimSpace(string(b)); id != "" {
+				return hashNode("machine-id", id), nil
+			}
+			errs = append(errs, fmt.Errorf("%s is empty", path))
+		}
+		return nil, fmt.Errorf("%w: no machine ID: %w", ErrNoHwAddressFound, errors.Join(errs...))
+	}
+}
+
+// HostnameHWAddrFunc returns an HWAddrFunc providing a node derived from the
+// hostname and, when running in a container, the container ID found in
+// /proc/self/cgroup, or in the paths of the files mounted by the container
+// runtime listed in /proc/self/mountinfo. Containers sharing a hostname
+// get different nodes, and a container keeps its node across restarts.
+func HostnameHWAddrFunc() HWAddrFunc {
+	return func() (net.HardwareAddr, error) {
+		host, err := osHostname()
+		if err != nil {
+			return nil, fmt.Errorf("%w: no hostname: %w", ErrNoHwAddressFound, err)
+		}
+		return hashNode("hostname", host, containerID()), nil
+	}
+}
+
+// EnvHWAddrFunc returns an HWAddrFunc providing the node set in the
+// environment variable name, in any format accepted by net.ParseMAC for a
+// 48-bit address, such as "02:00:5e:10:00:01". When the variable is not set,
+// the node is provided by fallback, or by the network interfaces when
+// fallback is nil. An invalid node in the variable is an error.
+func EnvHWAddrFunc(name string, fallback HWAddrFunc) HWAddrFunc {
+	if fallback == nil {
+		fallback = defaultHWAddrFunc
+	}
+	return func() (net.HardwareAddr, error) {
+		s, ok := os.LookupEnv(name)
+		if !ok || s == "" {
+			return fallback()
+		}
+		return parseNode(s)
+	}
+}
+
+// FileHWAddrFunc returns an HWAddrFunc providing a random node persisted to
+// the file at path, so the node survives restarts when the file is on a
+// persistent volume. The first call creates the file with a new random node,
+// with the multicast bit set as recommended by RFC 9562; later calls, in this
+// process or others, read it back. The node is stored as text, in the format
+// of net.HardwareAddr.String. The file is written to a temporary file in the
+// same directory and linked into place, so other processes never read a
+// partial file.
+func FileHWAddrFunc(path string) HWAddrFunc {
+	return func() (net.HardwareAddr, error) {
+		node, err := readNodeFile(path)
+		if !errors.Is(err, fs.ErrNotExist) {
+			return node, err
+		}
+
+		node, err = randomNode()
+		if err != nil {
+			return nil, err
+		}
+		tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+		if err != nil {
+			return nil, err
+		}
+		defer os.Remove(tmp.Name())
+		err = tmp.Chmod(0o644)
+		if err == nil {
+			_, err = fmt.Fprintln(tmp, node)
+		}
+		if err == nil {
+			err = tmp.Sync()
+		}
+		if cerr := tmp.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			return nil, err
+		}
+		err = os.Link(tmp.Name(), path)
+		if errors.Is(err, fs.ErrExist) {
+			// another process created the file first
+			return readNodeFile(path)
+		}
+		if err != nil {
+			return nil, err
+		}
+		return node, nil
+	}
+}
+
+// NodeLease is a node leased from a directory of lock files by LeaseNode.
+// Until the lease is closed, no other process leasing from the same
+// directory gets the same node.
+type NodeLease struct {
+	path string
+	node net.HardwareAddr
+
+	mu sync.Mutex
+	f  *os.File
+}
+
+// LeaseNode leases a node from the directory dir, shared by the processes
+// running on the same machine, so that they never share a node. The
+// directory holds up to slots lock files, each with a random node; the
+// first lock file not locked by another process is locked for the lifetime
+// of the lease, and the node it holds is leased. Nodes are reused by later
+// leases of the same slot, so restarted processes keep a small set of nodes.
+//
+// The lock is released when the lease is closed, or when the process exits.
+// Returns an error wrapping ErrNoHwAddressFound when all slots are leased.
+// Leasing is only supported on systems with flock(2).
+func LeaseNode(dir string, slots int) (*NodeLease, error) {
+	if slots <= 0 {
+		return nil, fmt.Errorf("uuid: cannot lease a node from %d slots", slots)
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	for i := 0; i < slots; i++ {
+		path := filepath.Join(dir, fmt.Sprintf("node-%d.lock", i))
+		f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o644)
+		if err != nil {
+			return nil, err
+		}
+		locked, err := tryLockFile(f)
+		if err != nil || !locked {
+			f.Close()
+			if err != nil {
+				return nil, fmt.Errorf("uuid: cannot lock %s: %w", path, err)
+			}
+			continue
+		}
+		node, err := leasedNode(f)
+		if err != nil {
+			f.Close()
+			return nil, fmt.Errorf("uuid: cannot lease node in %s: %w", path, err)
+		}
+		return &NodeLease{path: path, node: node, f: f}, nil
+	}
+	return nil, fmt.Errorf("%w: all %d nodes in %s are leased", ErrNoHwAddressFound, slots, dir)
+}
+
+// Node returns the leased node.
+func (l *NodeLease) Node() net.HardwareAddr {
+	return bytes.Clone(l.node)
+}
+
+// Path returns the path of the lock file of the lease.
+func (l *NodeLease) Path() string {
+	return l.path
+}
+
+// HWAddrFunc returns an HWAddrFunc providing the leased node, or an error
+// once the lease is closed.
+func (l *NodeLease) HWAddrFunc() HWAddrFunc {
+	return func() (net.HardwareAddr, error) {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		if l.f == nil {
+			return nil, fmt.Errorf("%w: node lease %s is closed", ErrNoHwAddressFound, l.path)
+		}
+		return l.Node(), nil
+	}
+}
+
+// Close releases the lease. A Gen that already read the node keeps using
+// it, so the generator should not be used after the lease is released.
+func (l *NodeLease) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.f == nil {
+		return nil
+	}
+	err := l.f.Close()
+	l.f = nil
+	return err
+}
+
+// leasedNode returns the node held by the locked lock file f, writing a new
+// random node to it if it has none.
+func leasedNode(f *os.File) (net.HardwareAddr, error) {
+	b, err := io.ReadAll(f)
+	if err != nil {
+		return nil, err
+	}
+	if s := strings.TrimSpace(string(b)); s != "" {
+		return parseNode(s)
+	}
+	node, err := randomNode()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := fmt.Fprintln(f, node); err != nil {
+		return nil, err
+	}
+	return node, f.Sync()
+}
+
+// readNodeFile returns the node stored in the file at path.
+func readNodeFile(path string) (net.HardwareAddr, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	node, err := parseNode(strings.TrimSpace(string(b)))
+	if err != nil {
+		return nil, fmt.Errorf("%w in %s", err, path)
+	}
+	return node, nil
+}
+
+// parseNode parses the 48-bit node s.
+func parseNode(s string) (net.HardwareAddr, error) {
+	node, err := net.ParseMAC(s)
+	if err != nil {
+		return nil, fmt.Errorf("uuid: invalid node %q: %w", s, err)
+	}
+	if len(node) != 6 {
+		return nil, fmt.Errorf("uuid: invalid node %q: not 48 bits", s)
+	}
+	return node, nil
+}
+
+// randomNode returns a random node with the multicast bit set.
+func randomNode() (net.HardwareAddr, error) {
+	node := make(net.HardwareAddr, 6)
+	if _, err := io.ReadFull(rand.Reader, node); err != nil {
+		return nil, err
+	}
+	node[0] |= 0x01
+	return node, nil
+}
+
+// hashNode returns a node derived from the SHA-256 hash of the provided
+// values, with the multicast bit set, as the node is not an IEEE 802 address.
+func hashNode(values ...string) net.HardwareAddr {
+	h := sha256.New()
+	h.Write([]byte("github.com/gofrs/uuid node"))
+	for _, v := range values {
+		h.Write([]byte{0})
+		h.Write([]byte(v))
+	}
+	node := net.HardwareAddr(h.Sum(nil)[:6])
+	node[0] |= 0x01
+	return node
+}
+
+var (
+	// cgroupIDPattern matches the 64 hexadecimal characters of the ID of a
+	// Docker, containerd or CRI-O container in its cgroup paths.
+	cgroupIDPattern = regexp.MustCompile(`\b[0-9a-f]{64}\b`)
+	// mountIDPattern matches the ID of a container in the paths of the files
+	// mounted into it by Docker or Podman, such as
+	// /var/lib/docker/containers/<id>/hostname. Other mounts hold 64
+	// hexadecimal characters that are not container IDs, such as the layers
+	// in /var/lib/docker/overlay2/<id>.
+	mountIDPattern = regexp.MustCompile(`[/-]containers/([0-9a-f]{64})/`)
+)
+
+// containerID returns the ID of the container of the process, found in its
+// cgroups or mounts, or "" when it does not run in a container.
+func containerID() string {
+	if id := findInFile(cgroupPath, cgroupIDPattern); id != "" {
+		return id
+	}
+	return findInFile(mountinfoPath, mountIDPattern)
+}
+
+// findInFile returns the last submatch of pattern in the first line of the
+// file at path matching it, or "" when no line matches.
+func findInFile(path string, pattern *regexp.Regexp) string {
+	f, err := os.Open(path)
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		if m := pattern.FindStringSubmatch(s.Text()); m != nil {
+			return m[len(m)-1]
+		}
+	}
+	return ""
+}
@@ -0,0 +1,256 @@
+// Copyright (C) 2013-2018 by Maxim Bublis <b@codemonkey.ru>
+//
+// Permission is hereby granted, free of charge, to any person obtaining
+// a copy of this software and associated documentation files (the
+// "Software"), to deal in the Software without restriction, including
+// without limitation the rights to use, copy, modify, merge, publish,
+// distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so, subject to
+// the following conditions:
+//
+// The above copyright notice and this permission notice shall be
+// included in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+// EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF
+// MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE
+// LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+// OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION
+// WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package uuid
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestMachineIDHWAddrFunc(t *testing.T) {
+	dir := t.TempDir()
+	etc, dbus := filepath.Join(dir, "etc"), filepath.Join(dir, "dbus")
+	saved := machineIDPaths
+	machineIDPaths = []string{etc, dbus}
+	defer func() { machineIDPaths = saved }()
+
+	if _, err := MachineIDHWAddrFunc()(); !errors.Is(err, ErrNoHwAddressFound) {
+		t.Errorf("without machine ID: error = %v, want %v", err, ErrNoHwAddressFound)
+	}
+
+	if err := os.WriteFile(dbus, []byte("0123456789abcdef0123456789abcdef\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	node, err := MachineIDHWAddrFunc()()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(node) != 6 || node[0]&0x01 == 0 {
+		t.Errorf("node = %v, want a 48-bit multicast node", node)
+	}
+	if got, want := node, hashNode("machine-id", "0123456789abcdef0123456789abcdef"); !bytes.Equal(got, want) {
+		t.Errorf("node = %v, want %v", got, want)
+	}
+
+	// /etc/machine-id takes precedence
+	if err := os.WriteFile(etc, []byte("fedcba9876543210fedcba9876543210\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if node2, err := MachineIDHWAddrFunc()(); err != nil || bytes.Equal(node2, node) {
+		t.Errorf("node = %v, %v, want a node other than %v", node2, err, node)
+	}
+}
+
+func TestHostnameHWAddrFunc(t *testing.T) {
+	dir := t.TempDir()
+	cgroup, mountinfo := filepath.Join(dir, "cgroup"), filepath.Join(dir, "mountinfo")
+	savedCgroup, savedMountinfo, savedHostname := cgroupPath, mountinfoPath, osHostname
+	cgroupPath, mountinfoPath = cgroup, mountinfo
+	osHostname = func() (string, error) { return "web-1", nil }
+	defer func() { cgroupPath, mountinfoPath, osHostname = savedCgroup, savedMountinfo, savedHostname }()
+
+	host, err := HostnameHWAddrFunc()()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := host, hashNode("hostname", "web-1", ""); !bytes.Equal(got, want) {
+		t.Errorf("node = %v, want %v", got, want)
+	}
+
+	// layer IDs in mounts are not container IDs
+	layer := strings.Repeat("a1", 32)
+	if err := os.WriteFile(mountinfo, []byte("1 0 0:1 / / rw - overlay overlay rw,upperdir=/var/lib/docker/overlay2/"+layer+"/diff\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if node, err := HostnameHWAddrFunc()(); err != nil || !bytes.Equal(node, host) {
+		t.Errorf("node with overlay mounts = %v, %v, want %v", node, err, host)
+	}
+
+	id := strings.Repeat("3f", 32)
+	if err := os.WriteFile(mountinfo, []byte("2 1 8:1 /var/lib/docker/containers/"+id+"/hostname /etc/hostname rw - ext4 /dev/sda1 rw\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if node, err := HostnameHWAddrFunc()(); err != nil || !bytes.Equal(node, hashNode("hostname", "web-1", id)) {
+		t.Errorf("node with container mounts = %v, %v, want %v", node, err, hashNode("hostname", "web-1", id))
+	}
+
+	if err := os.WriteFile(mountinfo, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(cgroup, []byte("0::/system.slice/docker-"+id+".scope\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	container, err := HostnameHWAddrFunc()()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := container, hashNode("hostname", "web-1", id); !bytes.Equal(got, want) {
+		t.Errorf("node in container = %v, want %v", got, want)
+	}
+	if bytes.Equal(host, container) {
+		t.Errorf("node in container = %v, same as the host", container)
+	}
+
+	osHostname = func() (string, error) { return "", fmt.Errorf("no hostname") }
+	if _, err := HostnameHWAddrFunc()(); !errors.Is(err, ErrNoHwAddressFound) {
+		t.Errorf("without hostname: error = %v, want %v", err, ErrNoHwAddressFound)
+	}
+}
+
+func TestEnvHWAddrFunc(t *testing.T) {
+	const name = "UUID_TEST_NODE"
+	fallback := net.HardwareAddr{1, 2, 3, 4, 5, 6}
+	fn := EnvHWAddrFunc(name, func() (net.HardwareAddr, error) { return fallback, nil })
+
+	t.Setenv(name, "")
+	if node, err := fn(); err != nil || !bytes.Equal(node, fallback) {
+		t.Errorf("unset: node = %v, %v, want %v", node, err, fallback)
+	}
+
+	t.Setenv(name, "02-00-5E-10-00-01")
+	if node, err := fn(); err != nil || node.String() != "02:00:5e:10:00:01" {
+		t.Errorf("set: node = %v, %v, want 02:00:5e:10:00:01", node, err)
+	}
+
+	for _, v := range []string{"not a node", "00:00:00:00:fe:80:00:00:00:00:00:00:02:00:5e:10:00:00:00:01"} {
+		t.Setenv(name, v)
+		if node, err := fn(); err == nil {
+			t.Errorf("%q: node = %v, want error", v, node)
+		}
+	}
+}
+
+func TestFileHWAddrFunc(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "node")
+	node, err := FileHWAddrFunc(path)()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(node) != 6 || node[0]&0x01 == 0 {
+		t.Errorf("node = %v, want a 48-bit multicast node", node)
+	}
+	if b, err := os.ReadFile(path); err != nil || strings.TrimSpace(string(b)) != node.String() {
+		t.Errorf("file = %q, %v, want %q", b, err, node)
+	}
+	if again, err := FileHWAddrFunc(path)(); err != nil || !bytes.Equal(again, node) {
+		t.Errorf("second call: node = %v, %v, want %v", again, err, node)
+	}
+
+	if err := os.WriteFile(path, []byte("garbage"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if node, err := FileHWAddrFunc(path)(); err == nil {
+		t.Errorf("invalid file: node = %v, want error", node)
+	}
+	if node, err := FileHWAddrFunc(filepath.Join(path, "missing", "node"))(); err == nil {
+		t.Errorf("missing directory: node = %v, want error", node)
+	}
+
+	t.Run("Concurrent", func(t *testing.T) {
+		dir := t.TempDir()
+		path := filepath.Join(dir, "node")
+		nodes := make([]net.HardwareAddr, 8)
+		errs := make([]error, len(nodes))
+		var wg sync.WaitGroup
+		for i := range nodes {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				nodes[i], errs[i] = FileHWAddrFunc(path)()
+			}()
+		}
+		wg.Wait()
+		for i, node := range nodes {
+			if errs[i] != nil || !bytes.Equal(node, nodes[0]) {
+				t.Errorf("call %d: node = %v, %v, want %v", i, node, errs[i], nodes[0])
+			}
+		}
+		if entries, err := os.ReadDir(dir); err != nil || len(entries) != 1 {
+			t.Errorf("directory holds %v, %v, want only the node file", entries, err)
+		}
+	})
+}
+
+func TestLeaseNode(t *testing.T) {
+	if _, err := LeaseNode(t.TempDir(), 0); err == nil {
+		t.Error("LeaseNode() with no slots did not error")
+	}
+	switch runtime.GOOS {
+	case "darwin", "dragonfly", "freebsd", "linux", "netbsd", "openbsd":
+	default:
+		if _, err := LeaseNode(t.TempDir(), 1); !errors.Is(err, errors.ErrUnsupported) {
+			t.Errorf("LeaseNode() error = %v, want %v", err, errors.ErrUnsupported)
+		}
+		return
+	}
+
+	dir := filepath.Join(t.TempDir(), "nodes")
+	l1, err := LeaseNode(dir, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l1.Close()
+	l2, err := LeaseNode(dir, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if l1.Path() == l2.Path() || bytes.Equal(l1.Node(), l2.Node()) {
+		t.Errorf("leases %s (%v) and %s (%v) share a node", l1.Path(), l1.Node(), l2.Path(), l2.Node())
+	}
+	if _, err := LeaseNode(dir, 2); !errors.Is(err, ErrNoHwAddressFound) {
+		t.Errorf("LeaseNode() with all slots leased: error = %v, want %v", err, ErrNoHwAddressFound)
+	}
+
+	g := NewGenWithOptions(WithHWAddrFunc(l2.HWAddrFunc()))
+	u, err := g.NewV1()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := net.HardwareAddr(u[10:]); !bytes.Equal(got, l2.Node()) {
+		t.Errorf("NewV1() node = %v, want %v", got, l2.Node())
+	}
+
+	// a released slot is leased again with the same node
+	node := l2.Node()
+	if err := l2.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := l2.HWAddrFunc()(); !errors.Is(err, ErrNoHwAddressFound) {
+		t.Errorf("closed lease: error = %v, want %v", err, ErrNoHwAddressFound)
+	}
+	l3, err := LeaseNode(dir, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l3.Close()
+	if !bytes.Equal(l3.Node(), node) {
+		t.Errorf("new lease node = %v, want %v", l3.Node(), node)
+	}
+}
@@ -0,0 +1,91 @@
+// Copyright (C) 2013-2018 by Maxim Bublis <b@codemonkey.ru>
+//
+// Permission is hereby granted, free of charge, to any person obtaining
+// a copy of this software and associated documentation files (the
+// "Software"), to deal in the Software without restriction, including
+// without limitation the rights to use, copy, modify, merge, publish,
+// distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so, subject to
+// the following conditions:
+//
+// The above copyright notice and this permission notice shall be
+// included in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+// EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF
+// MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE
+// LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+// OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION
+// WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package uuid
+
+import (
+	"io"
+	"sync"
+)
+
+// WithRandomPool is a GenOption that makes the generator read random bytes
+// from its random reader in chunks of size bytes, and serve them from a
+// buffer. This reduces the number of reads from a slow random source, such
+// as a custom reader backed by a syscall or an HSM, to one per size bytes.
+// Reads of size bytes or more bypass the buffer.
+//
+// The pool applies to the random reader set by WithRandomReader, or to the
+// default rand.Reader, whatever the order of the options.
+//
+// Buffered bytes stay in memory until they are used, and are shared by all
+// the UUIDs of the generator. Where this is not acceptable, such as when
+// UUIDs are used as secrets, leave the pool disabled: it is disabled by
+// default, and a size of zero or less disables it.
+func WithRandomPool(size int) GenOption {
+	return func(gen *Gen) {
+		gen.randomPoolSize = size
+	}
+}
+
+// randomPool is an io.Reader that reads from r in chunks of len(buf) bytes.
+// Bytes are wiped from buf once they are read.
+type randomPool struct {
+	mu  sync.Mutex
+	r   io.Reader
+	buf []byte
+	off int // start of the unread bytes in buf
+}
+
+func newRandomPool(r io.Reader, size int) *randomPool {
+	return &randomPool{
+		r:   r,
+		buf: make([]byte, size),
+		off: size,
+	}
+}
+
+// Read implements the io.Reader interface. It only returns fewer than
+// len(b) bytes when reading from the underlying reader fails.
+func (p *randomPool) Read(b []byte) (int, error) {
+	if len(b) >= len(p.buf) {
+		return io.ReadFull(p.r, b)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	n := 0
+	for n < len(b) {
+		if p.off == len(p.buf) {
+			if _, err := io.ReadFull(p.r, p.buf); err != nil {
+				// do not keep a partial chunk
+				clear(p.buf)
+				return n, err
+			}
+			p.off = 0
+		}
+		c := copy(b[n:], p.buf[p.off:])
+		clear(p.buf[p.off : p.off+c])
+		p.off += c
+		n += c
+	}
+	return n, nil
+}
@@ -0,0 +1,126 @@
+// Copyright (C) 2013-2018 by Maxim Bublis <b@codemonkey.ru>
+//
+// Permission is hereby granted, free of charge, to any person obtaining
+// a copy of this software and associated documentation files (the
+// "Software"), to deal in the Software without restriction, including
+// without limitation the rights to use, copy, modify, merge, publish,
+// distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so, subject to
+// the following conditions:
+//
+// The above copyright notice and this permission notice shall be
+// included in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+// EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF
+// MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE
+// LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+// OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION
+// WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package uuid
+
+import (
+	"bytes"
+	"crypto/rand"
+	"errors"
+	"io"
+	"testing"
+)
+
+// countingReader counts the reads from r.
+type countingReader struct {
+	r     io.Reader
+	reads int
+}
+
+func (c *countingReader) Read(b []byte) (int, error) {
+	c.reads++
+	return c.r.Read(b)
+}
+
+func TestRandomPool(t *testing.T) {
+	t.Run("Chunks", func(t *testing.T) {
+		src := &countingReader{r: rand.Reader}
+		g := NewGenWithOptions(WithRandomPool(1024), WithRandomReader(src))
+		for range 64 {
+			if _, err := g.NewV4(); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if src.reads != 1 {
+			t.Errorf("read %d times from the random reader, want 1", src.reads)
+		}
+		if _, err := g.NewV4(); err != nil {
+			t.Fatal(err)
+		}
+		if src.reads != 2 {
+			t.Errorf("read %d times from the random reader, want 2", src.reads)
+		}
+	})
+	t.Run("Sequence", func(t *testing.T) {
+		data := make([]byte, 100)
+		for i := range data {
+			data[i] = byte(i)
+		}
+		p := newRandomPool(bytes.NewReader(data), 10)
+		got := make([]byte, 0, len(data))
+		for _, n := range []int{3, 7, 9, 1, 4, 50, 5, 3} {
+			b := make([]byte, n)
+			if _, err := io.ReadFull(p, b); err != nil {
+				t.Fatal(err)
+			}
+			got = append(got, b...)
+		}
+		// the 50-byte read bypasses the pool, which still holds 6 bytes
+		var want []byte
+		for _, r := range [][2]int{{0, 24}, {30, 80}, {24, 30}, {80, 82}} {
+			want = append(want, data[r[0]:r[1]]...)
+		}
+		if !bytes.Equal(got, want) {
+			t.Errorf("read %v, want %v", got, want)
+		}
+	})
+	t.Run("Wiped", func(t *testing.T) {
+		p := newRandomPool(rand.Reader, 64)
+		b := make([]byte, 16)
+		if _, err := p.Read(b); err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(p.buf[:16], make([]byte, 16)) {
+			t.Error("pool kept bytes that were read")
+		}
+	})
+	t.Run("Disabled", func(t *testing.T) {
+		for _, size := range []int{0, -1} {
+			src := &countingReader{r: rand.Reader}
+			g := NewGenWithOptions(WithRandomReader(src), WithRandomPool(size))
+			if g.rand != src {
+				t.Errorf("WithRandomPool(%d) wrapped the random reader", size)
+			}
+		}
+	})
+	t.Run("FaultyReader", func(t *testing.T) {
+		g := NewGenWithOptions(
+			WithRandomReader(&faultyReader{readToFail: 0}),
+			WithRandomPool(1024),
+		)
+		u, err := g.NewV4()
+		testErrCheck(t, "NewV4()", "faulty", err)
+		if u != Nil {
+			t.Errorf("got %v on error, want Nil", u)
+		}
+		// the next chunk is read successfully
+		if _, err = g.NewV4(); err != nil {
+			t.Fatal(err)
+		}
+	})
+	t.Run("Short", func(t *testing.T) {
+		p := newRandomPool(io.LimitReader(rand.Reader, 5), 10)
+		n, err := p.Read(make([]byte, 4))
+		if n != 0 || !errors.Is(err, io.ErrUnexpectedEOF) {
+			t.Errorf("Read() = %d, %v, want 0, %v", n, err, io.ErrUnexpectedEOF)
+		}
+	})
+}
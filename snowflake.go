@@ -0,0 +1,163 @@
+// Copyright (C) 2013-2018 by Maxim Bublis <b@codemonkey.ru>
+//
+// Permission is hereby granted, free of charge, to any person obtaining
+// a copy of this software and associated documentation files (the
+// "Software"), to deal in the Software without restriction, including
+// without limitation the rights to use, copy, modify, merge, publish,
+// distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so, subject to
+// the following conditions:
+//
+// The above copyright notice and this permission notice shall be
+// included in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+// EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF
+// MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE
+// LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+// OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION
+// WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package uuid
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"time"
+)
+
+// Sizes of the fields of a snowflake V8 UUID, in bits.
+const (
+	snowflakeWorkerBits   = 10
+	snowflakeSequenceBits = 12
+	snowflakeRandomBits   = 52
+)
+
+// SnowflakeGen generates V8 UUIDs with the layout of Twitter Snowflake IDs:
+// a timestamp, the ID of the worker that generated the UUID, and a sequence
+// number within the millisecond. The remaining bits are random. The fields
+// fill the 122 custom bits of the UUID in this order, as described by the
+// V8Layout "ts_ms:48, worker:10, seq:12, rand:52":
+//
+//   - ts_ms: the number of milliseconds since the Unix epoch, as in V7 UUIDs
+//   - worker: the worker ID, from 0 to 1023
+//   - seq: the sequence number, from 0 to 4095, starting from 0 every
+//     millisecond
+//   - rand: random bits
+//
+// UUIDs from a SnowflakeGen are strictly increasing, and UUIDs from several
+// workers sort by time first. When the sequence is exhausted within a
+// millisecond or the clock goes backwards, the MonotonicPolicy of the
+// underlying Gen applies, with MonotonicWrap behaving like MonotonicBorrow.
+// Use MonotonicBlock to wait for the next millisecond, like Snowflake does.
+type SnowflakeGen struct {
+	gen    *Gen
+	worker uint64
+	state  tickState
+}
+
+// NewSnowflakeGen returns a SnowflakeGen for the provided worker ID. It uses
+// the epoch function, random reader, MonotonicPolicy and MonotonicHook of g,
+// or of a new Gen when g is nil. Returns an error wrapping ErrV8FieldValue if
+// the worker ID does not fit in 10 bits.
+func NewSnowflakeGen(g *Gen, worker uint16) (*SnowflakeGen, error) {
+	if worker>>snowflakeWorkerBits != 0 {
+		return nil, fmt.Errorf("%w: worker ID %d does not fit in %d bits", ErrV8FieldValue, worker, snowflakeWorkerBits)
+	}
+	if g == nil {
+		g = NewGen()
+	}
+	return &SnowflakeGen{
+		gen:    g,
+		worker: uint64(worker),
+		state: tickState{
+			counterBits: snowflakeSequenceBits,
+			resolution:  time.Millisecond,
+			ticks:       snowflakeTicks,
+			tickTime:    snowflakeTime,
+		},
+	}, nil
+}
+
+// New returns a snowflake V8 UUID for the current time.
+func (s *SnowflakeGen) New() (UUID, error) {
+	return s.newV8(context.Background(), s.gen.epochFunc(), true)
+}
+
+// NewContext is like New, but when the generator uses the MonotonicBlock
+// policy, waiting for the clock to advance stops when ctx is done. In that
+// case the context's error is returned.
+func (s *SnowflakeGen) NewContext(ctx context.Context) (UUID, error) {
+	return s.newV8(ctx, s.gen.epochFunc(), true)
+}
+
+// NewAtTime returns a snowflake V8 UUID for the provided time.
+func (s *SnowflakeGen) NewAtTime(atTime time.Time) (UUID, error) {
+	return s.newV8(context.Background(), atTime, false)
+}
+
+func (s *SnowflakeGen) newV8(ctx context.Context, atTime time.Time, now bool) (UUID, error) {
+	var rnd [8]byte
+	if _, err := io.ReadFull(s.gen.rand, rnd[:]); err != nil {
+		return Nil, err
+	}
+
+	ts, seq, err := s.state.next(ctx, s.gen, atTime, now, 0)
+	if err != nil {
+		return Nil, err
+	}
+
+	x := uint128{lo: ts}.shl(v8Bits - 48)
+	x = x.or(uint128{lo: s.worker}.shl(v8Bits - 48 - snowflakeWorkerBits))
+	x = x.or(uint128{lo: seq << snowflakeRandomBits})
+	x = x.or(uint128{lo: binary.BigEndian.Uint64(rnd[:]) & (1<<snowflakeRandomBits - 1)})
+	return x.v8(), nil
+}
+
+// snowflakeTicks returns the unix_ts_ms of atTime.
+func snowflakeTicks(atTime time.Time) (uint64, error) {
+	if err := checkTimeRange(V7, atTime); err != nil {
+		return 0, err
+	}
+	return uint64(atTime.UnixMilli()), nil
+}
+
+// snowflakeTime returns the time of the provided unix_ts_ms.
+func snowflakeTime(ms uint64) time.Time {
+	return time.UnixMilli(int64(ms))
+}
+
+// WorkerFromUUID returns the worker ID embedded within a V8 UUID generated by
+// a SnowflakeGen. Returns an error if the UUID is any version other than 8.
+func WorkerFromUUID(u UUID) (uint16, error) {
+	x, err := v8Custom(u)
+	if err != nil {
+		return 0, err
+	}
+	return uint16(x.shr(v8Bits-48-snowflakeWorkerBits).lo & (1<<snowflakeWorkerBits - 1)), nil
+}
+
+// SequenceFromUUID returns the sequence number embedded within a V8 UUID
+// generated by a SnowflakeGen. Returns an error if the UUID is any version
+// other than 8.
+func SequenceFromUUID(u UUID) (uint16, error) {
+	x, err := v8Custom(u)
+	if err != nil {
+		return 0, err
+	}
+	return uint16(x.lo >> snowflakeRandomBits & (1<<snowflakeSequenceBits - 1)), nil
+}
+
+// TimeFromSnowflake returns the time embedded within a V8 UUID generated by a
+// SnowflakeGen, with millisecond precision. Returns an error if the UUID is
+// any version other than 8.
+func TimeFromSnowflake(u UUID) (time.Time, error) {
+	x, err := v8Custom(u)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return snowflakeTime(x.shr(v8Bits - 48).lo), nil
+}
@@ -0,0 +1,178 @@
+// Copyright (C) 2013-2018 by Maxim Bublis <b@codemonkey.ru>
+//
+// Permission is hereby granted, free of charge, to any person obtaining
+// a copy of this software and associated documentation files (the
+// "Software"), to deal in the Software without restriction, including
+// without limitation the rights to use, copy, modify, merge, publish,
+// distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so, subject to
+// the following conditions:
+//
+// The above copyright notice and this permission notice shall be
+// included in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+// EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF
+// MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE
+// LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+// OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION
+// WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package uuid
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestSnowflakeGen(t *testing.T) {
+	t.Run("Layout", testSnowflakeGenLayout)
+	t.Run("Sequence", testSnowflakeGenSequence)
+	t.Run("OverflowBlock", testSnowflakeGenOverflowBlock)
+	t.Run("InvalidWorker", testSnowflakeGenInvalidWorker)
+	t.Run("FaultyRand", testSnowflakeGenFaultyRand)
+	t.Run("WrongVersion", testSnowflakeGenWrongVersion)
+}
+
+func testSnowflakeGenLayout(t *testing.T) {
+	atTime := time.UnixMilli(1645557742000)
+	s, err := NewSnowflakeGen(nil, 1023)
+	if err != nil {
+		t.Fatal(err)
+	}
+	u, err := s.NewAtTime(atTime)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := u.Version(), V8; got != want {
+		t.Errorf("generated UUID with version %d, want %d", got, want)
+	}
+	if got, want := u.Variant(), VariantRFC9562; got != want {
+		t.Errorf("generated UUID with variant %d, want %d", got, want)
+	}
+	// the timestamp is where it is in V7 UUIDs
+	if got, want := u.String()[:14], "017f22e2-79b0-"; got != want {
+		t.Errorf("UUID %v starts with %q, want %q", u, got, want)
+	}
+
+	l, err := ParseV8Layout("ts_ms:48, worker:10, seq:12, rand:52")
+	if err != nil {
+		t.Fatal(err)
+	}
+	values, err := l.Decode(u)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if values["ts_ms"] != 1645557742000 || values["worker"] != 1023 || values["seq"] != 0 {
+		t.Errorf("Decode(%v) = %v", u, values)
+	}
+
+	if worker, err := WorkerFromUUID(u); err != nil || worker != 1023 {
+		t.Errorf("WorkerFromUUID(%v) = %d, %v, want 1023", u, worker, err)
+	}
+	if tm, err := TimeFromSnowflake(u); err != nil || !tm.Equal(atTime) {
+		t.Errorf("TimeFromSnowflake(%v) = %v, %v, want %v", u, tm, err, atTime)
+	}
+}
+
+func testSnowflakeGenSequence(t *testing.T) {
+	now := time.UnixMilli(1645557742000)
+	s, err := NewSnowflakeGen(NewGenWithOptions(WithEpochFunc(func() time.Time { return now })), 42)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var prev UUID
+	for i := 0; i < 5000; i++ {
+		u, err := s.New()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if bytes.Compare(prev[:], u[:]) >= 0 {
+			t.Fatalf("UUID %v is not greater than %v", u, prev)
+		}
+		prev = u
+
+		seq, err := SequenceFromUUID(u)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// the sequence restarts from 0 in the borrowed millisecond
+		if want := uint16(i % 4096); seq != want {
+			t.Fatalf("UUID %d has sequence %d, want %d", i, seq, want)
+		}
+		if worker, _ := WorkerFromUUID(u); worker != 42 {
+			t.Fatalf("UUID %d has worker %d, want 42", i, worker)
+		}
+	}
+	if tm, _ := TimeFromSnowflake(prev); !tm.Equal(now.Add(time.Millisecond)) {
+		t.Errorf("last UUID has time %v, want %v", tm, now.Add(time.Millisecond))
+	}
+}
+
+func testSnowflakeGenOverflowBlock(t *testing.T) {
+	clock := &testClock{t: time.UnixMilli(1645557742000)}
+	var events int
+	g := NewGenWithOptions(
+		WithEpochFunc(clock.Now),
+		WithMonotonicPolicy(MonotonicBlock),
+		WithMonotonicHook(func(ev MonotonicEvent) {
+			events++
+			// let time pass while the generator waits
+			clock.Set(ev.Time.Add(time.Millisecond))
+		}),
+	)
+	s, err := NewSnowflakeGen(g, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var last UUID
+	for i := 0; i < 4097; i++ {
+		if last, err = s.New(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if events != 1 {
+		t.Errorf("got %d events, want 1", events)
+	}
+	if tm, _ := TimeFromSnowflake(last); !tm.Equal(clock.Now()) {
+		t.Errorf("last UUID has time %v, want %v", tm, clock.Now())
+	}
+	if seq, _ := SequenceFromUUID(last); seq != 0 {
+		t.Errorf("last UUID has sequence %d, want 0", seq)
+	}
+}
+
+func testSnowflakeGenInvalidWorker(t *testing.T) {
+	if _, err := NewSnowflakeGen(nil, 1024); !errors.Is(err, ErrV8FieldValue) {
+		t.Errorf("NewSnowflakeGen(1024) error = %v, want %v", err, ErrV8FieldValue)
+	}
+}
+
+func testSnowflakeGenFaultyRand(t *testing.T) {
+	s, err := NewSnowflakeGen(NewGenWithOptions(WithRandomReader(&faultyReader{readToFail: 0})), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	u, err := s.New()
+	testErrCheck(t, "New()", "faulty", err)
+	if u != Nil {
+		t.Errorf("got %v on error, want Nil", u)
+	}
+}
+
+func testSnowflakeGenWrongVersion(t *testing.T) {
+	u := Must(NewV7())
+	if _, err := WorkerFromUUID(u); !errors.Is(err, ErrInvalidVersion) {
+		t.Errorf("WorkerFromUUID(%v) error = %v, want %v", u, err, ErrInvalidVersion)
+	}
+	if _, err := SequenceFromUUID(u); !errors.Is(err, ErrInvalidVersion) {
+		t.Errorf("SequenceFromUUID(%v) error = %v, want %v", u, err, ErrInvalidVersion)
+	}
+	if _, err := TimeFromSnowflake(u); !errors.Is(err, ErrInvalidVersion) {
+		t.Errorf("TimeFromSnowflake(%v) error = %v, want %v", u, err, ErrInvalidVersion)
+	}
+}
@@ -0,0 +1,124 @@
+// Copyright (C) 2013-2018 by Maxim Bublis <b@codemonkey.ru>
+//
+// Permission is hereby granted, free of charge, to any person obtaining
+// a copy of this software and associated documentation files (the
+// "Software"), to deal in the Software without restriction, including
+// without limitation the rights to use, copy, modify, merge, publish,
+// distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so, subject to
+// the following conditions:
+//
+// The above copyright notice and this permission notice shall be
+// included in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+// EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF
+// MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE
+// LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+// OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION
+// WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package uuid
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// State is the generator state that RFC 9562 section 6.3 recommends keeping
+// in stable storage, so that a restarted generator does not reuse the clock
+// sequence of a previous run.
+type State struct {
+	// LastTime is the timestamp of the most recently generated V1 or V6
+	// UUID, as a count of 100-nanosecond intervals since 15 October 1582.
+	LastTime uint64 `json:"last_time"`
+
+	// ClockSequence is the clock sequence used for that UUID.
+	ClockSequence uint16 `json:"clock_sequence"`
+
+	// LastV7 is the unix_ts_ms field of the most recently generated V7
+	// UUID, shifted left by 12 bits and combined with its rand_a field.
+	LastV7 uint64 `json:"last_v7,omitempty"`
+
+	// LastV7RandB holds the bits of rand_b that were part of the counter in
+	// that UUID, when using WithV7Counter or WithV7MonotonicRandom.
+	LastV7RandB uint64 `json:"last_v7_rand_b,omitempty"`
+}
+
+// StateStore is the interface implemented by stable-storage backends for a
+// Gen generator. See WithStateStore.
+type StateStore interface {
+	// Load returns the most recently saved State. It returns an error
+	// wrapping ErrStateNotFound when no state has been saved yet.
+	Load() (State, error)
+
+	// Save persists s, replacing any previously saved State.
+	Save(s State) error
+}
+
+// FileStateStore is a StateStore that keeps the generator state in a single
+// file. Saves are atomic: the state is written to a temporary file in the
+// same directory, which is then renamed over the previous file.
+//
+// A FileStateStore should only be used by one Gen at a time.
+type FileStateStore struct {
+	path string
+}
+
+// interface check -- build will fail if *FileStateStore doesn't satisfy StateStore
+var _ StateStore = (*FileStateStore)(nil)
+
+// NewFileStateStore returns a FileStateStore that reads and writes the
+// generator state at path. The file is not accessed until the first Load or
+// Save.
+func NewFileStateStore(path string) *FileStateStore {
+	return &FileStateStore{path: path}
+}
+
+// Load implements the StateStore interface.
+func (f *FileStateStore) Load() (State, error) {
+	var s State
+	b, err := os.ReadFile(f.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return s, fmt.Errorf("%w in %s", ErrStateNotFound, f.path)
+	}
+	if err != nil {
+		return s, err
+	}
+	if err = json.Unmarshal(b, &s); err != nil {
+		return State{}, fmt.Errorf("uuid: cannot decode state in %s: %w", f.path, err)
+	}
+	return s, nil
+}
+
+// Save implements the StateStore interface.
+func (f *FileStateStore) Save(s State) error {
+	b, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(f.path), filepath.Base(f.path)+".tmp*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err = tmp.Write(b); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err = tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), f.path)
+}
@@ -0,0 +1,212 @@
+// Copyright (C) 2013-2018 by Maxim Bublis <b@codemonkey.ru>
+//
+// Permission is hereby granted, free of charge, to any person obtaining
+// a copy of this software and associated documentation files (the
+// "Software"), to deal in the Software without restriction, including
+// without limitation the rights to use, copy, modify, merge, publish,
+// distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so, subject to
+// the following conditions:
+//
+// The above copyright notice and this permission notice shall be
+// included in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+// EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF
+// MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE
+// LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+// OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION
+// WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package uuid
+
+import (
+	"encoding/binary"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+type memStateStore struct {
+	state   State
+	saved   bool
+	saves   int
+	loadErr error
+	saveErr error
+}
+
+func (m *memStateStore) Load() (State, error) {
+	if m.loadErr != nil {
+		return State{}, m.loadErr
+	}
+	if !m.saved {
+		return State{}, ErrStateNotFound
+	}
+	return m.state, nil
+}
+
+func (m *memStateStore) Save(s State) error {
+	if m.saveErr != nil {
+		return m.saveErr
+	}
+	m.state, m.saved = s, true
+	m.saves++
+	return nil
+}
+
+func TestFileStateStore(t *testing.T) {
+	t.Run("RoundTrip", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "uuid.state")
+		store := NewFileStateStore(path)
+
+		want := State{LastTime: 139024162450000000, ClockSequence: 0x1234}
+		if err := store.Save(want); err != nil {
+			t.Fatal(err)
+		}
+		got, err := store.Load()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != want {
+			t.Errorf("Load() = %+v, want %+v", got, want)
+		}
+
+		entries, err := os.ReadDir(filepath.Dir(path))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(entries) != 1 {
+			t.Errorf("found %d files after Save, want 1", len(entries))
+		}
+	})
+	t.Run("Missing", func(t *testing.T) {
+		store := NewFileStateStore(filepath.Join(t.TempDir(), "uuid.state"))
+		if _, err := store.Load(); !errors.Is(err, ErrStateNotFound) {
+			t.Errorf("Load() error = %v, want %v", err, ErrStateNotFound)
+		}
+	})
+	t.Run("Corrupted", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "uuid.state")
+		if err := os.WriteFile(path, []byte("{"), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		_, err := NewFileStateStore(path).Load()
+		if err == nil || errors.Is(err, ErrStateNotFound) {
+			t.Errorf("Load() error = %v, want a decoding error", err)
+		}
+	})
+	t.Run("MissingDirectory", func(t *testing.T) {
+		store := NewFileStateStore(filepath.Join(t.TempDir(), "missing", "uuid.state"))
+		if err := store.Save(State{}); err == nil {
+			t.Error("Save() error = <nil>, want error")
+		}
+	})
+}
+
+func TestGenStateStore(t *testing.T) {
+	t.Run("Restore", func(t *testing.T) {
+		atTime := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+		store := &memStateStore{}
+		store.saved = true
+		store.state = State{
+			// The previous run saw a later time than the current clock.
+			LastTime:      uint64(atTime.Add(time.Hour).UnixNano()/100) + epochStart,
+			ClockSequence: 0x0042,
+		}
+
+		g := NewGenWithOptions(
+			WithEpochFunc(func() time.Time { return atTime }),
+			WithStateStore(store, 0),
+		)
+		u, err := g.NewV1()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, want := binary.BigEndian.Uint16(u[8:])&0x3fff, uint16(0x0043); got != want {
+			t.Errorf("clock sequence = %#04x, want %#04x", got, want)
+		}
+		if got, want := store.state.ClockSequence, uint16(0x0043); got != want {
+			t.Errorf("saved clock sequence = %#04x, want %#04x", got, want)
+		}
+		if got, want := store.state.LastTime, g.getEpoch(atTime); got != want {
+			t.Errorf("saved last time = %d, want %d", got, want)
+		}
+	})
+	t.Run("SaveInterval", func(t *testing.T) {
+		store := &memStateStore{}
+		g := NewGenWithOptions(WithStateStore(store, time.Hour))
+		for range 10 {
+			if _, err := g.NewV7(); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if store.saves != 1 {
+			t.Errorf("state saved %d times, want 1", store.saves)
+		}
+		if err := g.Close(); err != nil {
+			t.Fatal(err)
+		}
+		if store.saves != 2 {
+			t.Errorf("state saved %d times after Close, want 2", store.saves)
+		}
+		if err := g.Close(); err != nil {
+			t.Fatal(err)
+		}
+		if store.saves != 2 {
+			t.Errorf("state saved %d times after second Close, want 2", store.saves)
+		}
+	})
+	t.Run("CloseBeforeUse", func(t *testing.T) {
+		store := &memStateStore{}
+		g := NewGenWithOptions(WithStateStore(store, 0))
+		if err := g.Close(); err != nil {
+			t.Fatal(err)
+		}
+		if store.saved {
+			t.Error("Close saved state for an unused generator")
+		}
+	})
+	t.Run("CloseWithoutStore", func(t *testing.T) {
+		if err := NewGen().Close(); err != nil {
+			t.Fatal(err)
+		}
+	})
+	t.Run("FaultyStore", func(t *testing.T) {
+		loadErr := errors.New("load failed")
+		saveErr := errors.New("save failed")
+		store := &memStateStore{loadErr: loadErr, saveErr: saveErr}
+		g := NewGenWithOptions(WithStateStore(store, 0))
+		if _, err := g.NewV6(); err != nil {
+			t.Fatalf("NewV6() error = %v, want <nil>", err)
+		}
+		err := g.Close()
+		if !errors.Is(err, loadErr) {
+			t.Errorf("Close() error = %v, want %v", err, loadErr)
+		}
+		if !errors.Is(err, saveErr) {
+			t.Errorf("Close() error = %v, want %v", err, saveErr)
+		}
+	})
+	t.Run("File", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "uuid.state")
+		g1 := NewGenWithOptions(WithStateStore(NewFileStateStore(path), time.Hour))
+		if _, err := g1.NewV1(); err != nil {
+			t.Fatal(err)
+		}
+		if err := g1.Close(); err != nil {
+			t.Fatal(err)
+		}
+
+		g2 := NewGenWithOptions(WithStateStore(NewFileStateStore(path), time.Hour))
+		if _, err := g2.NewV1(); err != nil {
+			t.Fatal(err)
+		}
+		if g1.clockSequence != g2.clockSequence && g1.clockSequence+1 != g2.clockSequence {
+			t.Errorf("restored clock sequence %#04x, want %#04x or %#04x",
+				g2.clockSequence, g1.clockSequence, g1.clockSequence+1)
+		}
+	})
+}
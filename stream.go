@@ -0,0 +1,92 @@
+// Copyright (C) 2013-2018 by Maxim Bublis <b@codemonkey.ru>
+//
+// Permission is hereby granted, free of charge, to any person obtaining
+// a copy of this software and associated documentation files (the
+// "Software"), to deal in the Software without restriction, including
+// without limitation the rights to use, copy, modify, merge, publish,
+// distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so, subject to
+// the following conditions:
+//
+// The above copyright notice and this permission notice shall be
+// included in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+// EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF
+// MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE
+// LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+// OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION
+// WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package uuid
+
+// Stream returns a generator with the same configuration as g, but with its
+// own clock sequence, last timestamps and counters for time-based UUIDs.
+// UUIDs from a stream are monotonic within that stream only, and generating
+// them does not affect the ordering of UUIDs generated by g or by other
+// streams. This is useful for backfilling historical times with
+// NewV*AtTime, one stream per partition, while g keeps generating UUIDs for
+// the current time.
+//
+// Calling Stream again with the same key returns the same generator. Streams
+// are kept for the lifetime of g, so keys should come from a bounded set,
+// such as partition names. Streams share the node of g for V1 UUIDs, and do
+// not use the StateStore of g.
+func (g *Gen) Stream(key string) *Gen {
+	g.streamsMutex.Lock()
+	defer g.streamsMutex.Unlock()
+
+	if s, ok := g.streams[key]; ok {
+		return s
+	}
+	if g.streams == nil {
+		g.streams = make(map[string]*Gen)
+	}
+	s := g.child()
+	g.streams[key] = s
+	return s
+}
+
+// Stateless returns a generator with the same configuration as g, that keeps
+// no state between calls: every time-based UUID is generated with a new
+// random clock sequence or counter seed, as if by a new generator. UUIDs for
+// the same timestamp are therefore not ordered between calls, but generating
+// them never affects the UUIDs generated by g.
+//
+// V1 UUIDs for the same timestamp only differ by their random 14-bit clock
+// sequence, so by the birthday bound they are likely to collide after about
+// 2^7 calls. V2 UUIDs only keep 6 bits of the clock sequence, and replace the
+// low 32 bits of the timestamp with the local ID, so V2 UUIDs for the same
+// domain and local ID within about 7 minutes are likely to collide after
+// about 2^3 calls: stateless generators are unsuitable for V2 UUIDs. Use a
+// stream when V1 UUIDs for the same timestamp must be unique.
+//
+// Stateless generators share the node of g for V1 and V2 UUIDs, and do not use the
+// StateStore of g.
+func (g *Gen) Stateless() *Gen {
+	s := g.child()
+	s.stateless = true
+	return s
+}
+
+// child returns a new generator with the configuration of g and empty state.
+func (g *Gen) child() *Gen {
+	root := g
+	if g.parent != nil {
+		root = g.parent
+	}
+	return &Gen{
+		parent:          root,
+		rand:            g.rand,
+		epochFunc:       g.epochFunc,
+		hwAddrFunc:      g.hwAddrFunc,
+		simulateTicks:   g.simulateTicks,
+		timestampOffset: g.timestampOffset,
+		timestampFuzz:   g.timestampFuzz,
+		v7Method:        g.v7Method,
+		v7CounterBits:   g.v7CounterBits,
+		monotonicPolicy: g.monotonicPolicy,
+		monotonicHook:   g.monotonicHook,
+	}
+}
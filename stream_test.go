@@ -0,0 +1,121 @@
+// Copyright (C) 2013-2018 by Maxim Bublis <b@codemonkey.ru>
+//
+// Permission is hereby granted, free of charge, to any person obtaining
+// a copy of this software and associated documentation files (the
+// "Software"), to deal in the Software without restriction, including
+// without limitation the rights to use, copy, modify, merge, publish,
+// distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so, subject to
+// the following conditions:
+//
+// The above copyright notice and this permission notice shall be
+// included in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+// EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF
+// MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE
+// LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+// OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION
+// WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package uuid
+
+import (
+	"bytes"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestStream(t *testing.T) {
+	now := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	past := time.Date(2019, 6, 7, 8, 9, 10, 0, time.UTC)
+	g := NewGenWithOptions(
+		WithEpochFunc(func() time.Time { return now }),
+		WithMonotonicPolicy(MonotonicError),
+		WithHWAddrFunc(func() (net.HardwareAddr, error) {
+			return net.HardwareAddr{0, 1, 2, 3, 4, 42}, nil
+		}),
+	)
+
+	live, err := g.NewV7()
+	if err != nil {
+		t.Fatal(err)
+	}
+	lastV7, lastTime := g.lastV7, g.lastTime
+
+	s := g.Stream("orders")
+	if s != g.Stream("orders") {
+		t.Error("Stream() returned different generators for the same key")
+	}
+	if s == g.Stream("invoices") {
+		t.Error("Stream() returned the same generator for different keys")
+	}
+
+	var prev UUID
+	for i := 0; i < 100; i++ {
+		u, err := s.NewV7AtTime(past)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if bytes.Compare(prev[:], u[:]) >= 0 {
+			t.Fatalf("UUID %v is not greater than %v", u, prev)
+		}
+		prev = u
+	}
+	// a backfill in another stream starts from its own state
+	if _, err := g.Stream("invoices").NewV7AtTime(past.Add(-time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	u, err := s.NewV1AtTime(past)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := u[10:], []byte{0, 1, 2, 3, 4, 42}; !bytes.Equal(got, want) {
+		t.Errorf("stream node = %x, want %x", got, want)
+	}
+
+	if g.lastV7 != lastV7 || g.lastTime != lastTime {
+		t.Error("generating from a stream changed the state of the generator")
+	}
+	next, err := g.NewV7()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Compare(live[:], next[:]) >= 0 {
+		t.Errorf("UUID %v is not greater than %v", next, live)
+	}
+}
+
+func TestStateless(t *testing.T) {
+	atTime := time.Date(2019, 6, 7, 8, 9, 10, 0, time.UTC)
+	g := NewGenWithOptions(WithMonotonicPolicy(MonotonicError))
+	if _, err := g.NewV7(); err != nil {
+		t.Fatal(err)
+	}
+	lastV7 := g.lastV7
+
+	s := g.Stateless()
+	seen := make(map[UUID]bool)
+	for i := 0; i < 100; i++ {
+		// V1 UUIDs for the same time only differ by their 14-bit clock
+		// sequence, which may repeat after a few calls.
+		for _, fn := range []func(time.Time) (UUID, error){s.NewV1AtTime, s.NewV6AtTime, s.NewV7AtTime} {
+			u, err := fn(atTime)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if seen[u] && u.Version() != V1 {
+				t.Fatalf("generated %v twice", u)
+			}
+			seen[u] = true
+		}
+	}
+	if s.lastV7Set || s.lastTime != 0 {
+		t.Error("stateless generator kept state")
+	}
+	if g.lastV7 != lastV7 {
+		t.Error("generating from a stateless generator changed the state of the generator")
+	}
+}
@@ -0,0 +1,279 @@
+// Copyright (C) 2013-2018 by Maxim Bublis <b@codemonkey.ru>
+//
+// Permission is hereby granted, free of charge, to any person obtaining
+// a copy of this software and associated documentation files (the
+// "Software"), to deal in the Software without restriction, including
+// without limitation the rights to use, copy, modify, merge, publish,
+// distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so, subject to
+// the following conditions:
+//
+// The above copyright notice and this permission notice shall be
+// included in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+// EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF
+// MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE
+// LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+// OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION
+// WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package uuid
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math/bits"
+	"sync"
+	"time"
+)
+
+// TimeV8Config configures a TimeV8Gen.
+type TimeV8Config struct {
+	// Epoch is the time of the zero timestamp. The zero value is the Unix
+	// epoch.
+	Epoch time.Time
+
+	// Resolution is the duration of a tick of the timestamp, between one
+	// nanosecond and one second. The zero value is 10 nanoseconds, as in RFC
+	// 9562 appendix B.1, which covers about 365 years from the epoch. A
+	// resolution of one nanosecond covers about 36 years.
+	Resolution time.Duration
+
+	// CounterBits is the number of bits, from 0 to 62, of the counter that
+	// orders UUIDs within a tick.
+	CounterBits int
+}
+
+// TimeV8Gen generates time-based V8 UUIDs with a custom epoch, resolution and
+// counter width, as described in RFC 9562 appendix B.1.
+//
+// The timestamp is a 60-bit count of ticks since the epoch, held in custom_a
+// and custom_b. The counter fills the leading bits of custom_c, and the rest
+// of custom_c is random. With the zero TimeV8Config, the UUIDs have the
+// layout of the example in RFC 9562 appendix B.1.
+//
+// UUIDs from a TimeV8Gen are strictly increasing. The counter is reseeded
+// with a random value every tick, with its most significant bit cleared, and
+// incremented within a tick. When the counter overflows or the clock goes
+// backwards, the MonotonicPolicy of the underlying Gen applies, with
+// MonotonicWrap behaving like MonotonicBorrow.
+type TimeV8Gen struct {
+	gen   *Gen
+	cfg   TimeV8Config
+	state tickState
+}
+
+// NewTimeV8Gen returns a TimeV8Gen configured with cfg. It uses the epoch
+// function, random reader, MonotonicPolicy and MonotonicHook of g, or of a
+// new Gen when g is nil. Returns an error wrapping ErrInvalidTimeV8Config if
+// cfg is invalid.
+func NewTimeV8Gen(g *Gen, cfg TimeV8Config) (*TimeV8Gen, error) {
+	if g == nil {
+		g = NewGen()
+	}
+	if cfg.Epoch.IsZero() {
+		cfg.Epoch = time.Unix(0, 0)
+	}
+	if cfg.Resolution == 0 {
+		cfg.Resolution = 10 * time.Nanosecond
+	}
+	if cfg.Resolution < time.Nanosecond || cfg.Resolution > time.Second {
+		return nil, fmt.Errorf("%w: resolution %v is not between 1ns and 1s", ErrInvalidTimeV8Config, cfg.Resolution)
+	}
+	if cfg.CounterBits < 0 || cfg.CounterBits > 62 {
+		return nil, fmt.Errorf("%w: counter of %d bits is not between 0 and 62 bits", ErrInvalidTimeV8Config, cfg.CounterBits)
+	}
+	t := &TimeV8Gen{gen: g, cfg: cfg}
+	t.state = tickState{
+		counterBits: cfg.CounterBits,
+		resolution:  cfg.Resolution,
+		ticks:       t.ticks,
+		tickTime:    t.tickTime,
+	}
+	return t, nil
+}
+
+// New returns a time-based V8 UUID for the current time.
+func (t *TimeV8Gen) New() (UUID, error) {
+	return t.newV8(context.Background(), t.gen.epochFunc(), true)
+}
+
+// NewContext is like New, but when the generator uses the MonotonicBlock
+// policy, waiting for the clock to advance stops when ctx is done. In that
+// case the context's error is returned.
+func (t *TimeV8Gen) NewContext(ctx context.Context) (UUID, error) {
+	return t.newV8(ctx, t.gen.epochFunc(), true)
+}
+
+// NewAtTime returns a time-based V8 UUID for the provided time. Returns an
+// error wrapping ErrTimeOutOfRange if the time is before the epoch, or too
+// far after it for a 60-bit timestamp.
+func (t *TimeV8Gen) NewAtTime(atTime time.Time) (UUID, error) {
+	return t.newV8(context.Background(), atTime, false)
+}
+
+// Time returns the time embedded within a V8 UUID generated with the same
+// configuration, truncated to the resolution. Returns an error if the UUID
+// is any version other than 8.
+func (t *TimeV8Gen) Time(u UUID) (time.Time, error) {
+	if u.Version() != V8 {
+		return time.Time{}, fmt.Errorf("%w %s is version %d, not version 8", ErrInvalidVersion, u, u.Version())
+	}
+	hi := binary.BigEndian.Uint64(u[0:8])
+	ticks := hi>>16<<12 | hi&0xfff
+	return t.tickTime(ticks), nil
+}
+
+func (t *TimeV8Gen) newV8(ctx context.Context, atTime time.Time, now bool) (UUID, error) {
+	var u UUID
+
+	// The first 8 bytes fill custom_c, and the following 8 seed the counter.
+	n := 8
+	if t.cfg.CounterBits > 0 {
+		n = 16
+	}
+	var rnd [16]byte
+	if _, err := io.ReadFull(t.gen.rand, rnd[:n]); err != nil {
+		return Nil, err
+	}
+	seed := binary.BigEndian.Uint64(rnd[8:])
+
+	var counter uint64
+	if cb := t.cfg.CounterBits; cb > 1 {
+		counter = seed >> (64 - cb + 1)
+	}
+	ticks, counter, err := t.state.next(ctx, t.gen, atTime, now, counter)
+	if err != nil {
+		return Nil, err
+	}
+
+	binary.BigEndian.PutUint64(u[0:8], ticks>>12<<16|ticks&0xfff)
+	copy(u[8:16], rnd[:8])
+	if cb := t.cfg.CounterBits; cb > 0 {
+		mask := (uint64(1)<<cb - 1) << (62 - cb)
+		c := binary.BigEndian.Uint64(u[8:16])
+		binary.BigEndian.PutUint64(u[8:16], c&^mask|counter<<(62-cb))
+	}
+	u.SetVersion(V8)
+	u.SetVariant(VariantRFC9562)
+	return u, nil
+}
+
+// tickState is the monotonic state of a generator of V8 UUIDs made of a
+// timestamp and a counter.
+type tickState struct {
+	counterBits int
+	resolution  time.Duration
+	ticks       func(time.Time) (uint64, error)
+	tickTime    func(uint64) time.Time
+
+	mu          sync.Mutex
+	lastTicks   uint64
+	lastCounter uint64
+	lastSet     bool
+	lastClock   uint64
+}
+
+// next returns the tick and counter of a new UUID for atTime, where counter
+// is the initial value of the counter for a new tick. When the counter
+// overflows or the clock goes backwards, the MonotonicPolicy of g applies,
+// with MonotonicWrap behaving like MonotonicBorrow. When now is true, atTime
+// was read from the epochFunc of g, and it is read again if the
+// MonotonicBlock policy needs to wait for the clock.
+func (s *tickState) next(ctx context.Context, g *Gen, atTime time.Time, now bool, counter uint64) (uint64, uint64, error) {
+	var reported bool
+	for {
+		ticks, err := s.ticks(atTime)
+		if err != nil {
+			return 0, 0, err
+		}
+		ticks, c, ev, wait := s.advance(g, atTime, ticks, counter)
+		if ev.Err == nil {
+			return ticks, c, nil
+		}
+		if err := g.handleMonotonicEvent(ctx, ev, now, wait, &reported); err != nil {
+			return 0, 0, err
+		}
+		if ev.Policy == MonotonicBorrow {
+			return ticks, c, nil
+		}
+		atTime = g.epochFunc()
+	}
+}
+
+// advance advances the state to the provided tick, and returns the tick and
+// counter of the new UUID. When the counter overflowed or the clock went
+// backwards, it returns a MonotonicEvent and, if the policy rejects the
+// tick, how long to wait for the next one; in that case, the state is left
+// untouched.
+func (s *tickState) advance(g *Gen, atTime time.Time, ticks, counter uint64) (uint64, uint64, MonotonicEvent, time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	policy := g.monotonicPolicy
+	if policy == MonotonicWrap {
+		policy = MonotonicBorrow
+	}
+
+	clock := ticks
+	var ev MonotonicEvent
+	if s.lastSet && ticks <= s.lastTicks {
+		ticks, counter = s.lastTicks, s.lastCounter+1
+		if counter>>s.counterBits != 0 {
+			ticks, counter = ticks+1, 0
+			ev.Err = ErrClockSequenceOverflow
+		}
+		if clock < s.lastClock {
+			ev.Err = ErrClockRollback
+		}
+	}
+	if ev.Err != nil {
+		ev.Version = V8
+		ev.Policy = policy
+		ev.Time = atTime
+		ev.Last = s.tickTime(s.lastTicks)
+		if policy != MonotonicBorrow {
+			return 0, 0, ev, ev.Last.Add(s.resolution).Sub(atTime)
+		}
+	}
+
+	s.lastTicks, s.lastCounter, s.lastSet, s.lastClock = ticks, counter, true, clock
+	return ticks, counter, ev, 0
+}
+
+// ticks returns the number of ticks between the epoch and atTime.
+func (t *TimeV8Gen) ticks(atTime time.Time) (uint64, error) {
+	secs := atTime.Unix() - t.cfg.Epoch.Unix()
+	nsecs := int64(atTime.Nanosecond() - t.cfg.Epoch.Nanosecond())
+	if nsecs < 0 {
+		secs--
+		nsecs += 1e9
+	}
+	if secs < 0 {
+		return 0, fmt.Errorf("%w: %s is before the V8 epoch %s", ErrTimeOutOfRange,
+			atTime.UTC().Format(time.RFC3339Nano), t.cfg.Epoch.UTC().Format(time.RFC3339Nano))
+	}
+
+	hi, lo := bits.Mul64(uint64(secs), 1e9)
+	lo, carry := bits.Add64(lo, uint64(nsecs), 0)
+	hi += carry
+	res := uint64(t.cfg.Resolution)
+	if hi < res {
+		if ticks, _ := bits.Div64(hi, lo, res); ticks < 1<<60 {
+			return ticks, nil
+		}
+	}
+	return 0, fmt.Errorf("%w: %s is too far after the V8 epoch %s", ErrTimeOutOfRange,
+		atTime.UTC().Format(time.RFC3339Nano), t.cfg.Epoch.UTC().Format(time.RFC3339Nano))
+}
+
+// tickTime returns the time of the provided tick.
+func (t *TimeV8Gen) tickTime(ticks uint64) time.Time {
+	hi, lo := bits.Mul64(ticks, uint64(t.cfg.Resolution))
+	secs, nsecs := bits.Div64(hi, lo, 1e9)
+	return time.Unix(t.cfg.Epoch.Unix()+int64(secs), int64(t.cfg.Epoch.Nanosecond())+int64(nsecs))
+}
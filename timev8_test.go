@@ -0,0 +1,254 @@
+// Copyright (C) 2013-2018 by Maxim Bublis <b@codemonkey.ru>
+//
+// Permission is hereby granted, free of charge, to any person obtaining
+// a copy of this software and associated documentation files (the
+// "Software"), to deal in the Software without restriction, including
+// without limitation the rights to use, copy, modify, merge, publish,
+// distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so, subject to
+// the following conditions:
+//
+// The above copyright notice and this permission notice shall be
+// included in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+// EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF
+// MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE
+// LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+// OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION
+// WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package uuid
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestTimeV8Gen(t *testing.T) {
+	t.Run("TestVector", testTimeV8GenTestVector)
+	t.Run("Time", testTimeV8GenTime)
+	t.Run("Monotonic", testTimeV8GenMonotonic)
+	t.Run("Overflow", testTimeV8GenOverflow)
+	t.Run("Rollback", testTimeV8GenRollback)
+	t.Run("TimeRange", testTimeV8GenTimeRange)
+	t.Run("InvalidConfig", testTimeV8GenInvalidConfig)
+	t.Run("FaultyRand", testTimeV8GenFaultyRand)
+}
+
+func testTimeV8GenTestVector(t *testing.T) {
+	// RFC 9562 appendix B.1: a 60-bit timestamp of 10ns ticks since the Unix
+	// epoch, followed by 62 random bits.
+	pRand := make([]byte, 8)
+	binary.BigEndian.PutUint64(pRand, 0x0EC932D5F69181C0)
+	g := NewGenWithOptions(
+		WithEpochFunc(func() time.Time {
+			return time.Date(2022, 2, 22, 14, 22, 22, 0, time.FixedZone("EST", -5*3600))
+		}),
+		WithRandomReader(bytes.NewReader(pRand)),
+	)
+	tg, err := NewTimeV8Gen(g, TimeV8Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	u, err := tg.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := u.String(), "2489e9ad-2ee2-8e00-8ec9-32d5f69181c0"; got != want {
+		t.Errorf("New() = %s, want %s", got, want)
+	}
+}
+
+func testTimeV8GenTime(t *testing.T) {
+	tests := []struct {
+		cfg    TimeV8Config
+		atTime time.Time
+		want   time.Time
+	}{
+		{
+			cfg:    TimeV8Config{},
+			atTime: time.Date(2024, 1, 2, 3, 4, 5, 123456789, time.UTC),
+			want:   time.Date(2024, 1, 2, 3, 4, 5, 123456780, time.UTC),
+		},
+		{
+			cfg:    TimeV8Config{Epoch: time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC), Resolution: time.Nanosecond},
+			atTime: time.Date(2024, 1, 2, 3, 4, 5, 123456789, time.UTC),
+			want:   time.Date(2024, 1, 2, 3, 4, 5, 123456789, time.UTC),
+		},
+		{
+			cfg:    TimeV8Config{Epoch: time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC), Resolution: time.Microsecond, CounterBits: 14},
+			atTime: time.Date(2024, 1, 2, 3, 4, 5, 123456789, time.UTC),
+			want:   time.Date(2024, 1, 2, 3, 4, 5, 123456000, time.UTC),
+		},
+		{
+			cfg:    TimeV8Config{Epoch: time.Date(2000, 1, 1, 0, 0, 0, 500, time.UTC), Resolution: time.Millisecond, CounterBits: 62},
+			atTime: time.Date(3000, 6, 7, 8, 9, 10, 11, time.UTC),
+			want:   time.Date(3000, 6, 7, 8, 9, 9, 999000500, time.UTC),
+		},
+		{
+			cfg:    TimeV8Config{Resolution: time.Second},
+			atTime: time.Date(1970, 1, 1, 0, 0, 0, 999999999, time.UTC),
+			want:   time.Unix(0, 0),
+		},
+	}
+	for _, tt := range tests {
+		tg, err := NewTimeV8Gen(nil, tt.cfg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		u, err := tg.NewAtTime(tt.atTime)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, want := u.Version(), V8; got != want {
+			t.Errorf("generated UUID with version %d, want %d", got, want)
+		}
+		if got, want := u.Variant(), VariantRFC9562; got != want {
+			t.Errorf("generated UUID with variant %d, want %d", got, want)
+		}
+		got, err := tg.Time(u)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !got.Equal(tt.want) {
+			t.Errorf("%+v: Time() = %v, want %v", tt.cfg, got, tt.want)
+		}
+	}
+
+	tg, err := NewTimeV8Gen(nil, TimeV8Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tg.Time(Must(NewV4())); !errors.Is(err, ErrInvalidVersion) {
+		t.Errorf("Time(V4) error = %v, want %v", err, ErrInvalidVersion)
+	}
+}
+
+func testTimeV8GenMonotonic(t *testing.T) {
+	now := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	g := NewGenWithOptions(WithEpochFunc(func() time.Time { return now }))
+	for _, cb := range []int{0, 1, 8, 62} {
+		tg, err := NewTimeV8Gen(g, TimeV8Config{CounterBits: cb})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var prev UUID
+		for i := 0; i < 1000; i++ {
+			u, err := tg.New()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if bytes.Compare(prev[:], u[:]) >= 0 {
+				t.Fatalf("counter of %d bits: UUID %v is not greater than %v", cb, u, prev)
+			}
+			prev = u
+		}
+	}
+}
+
+func testTimeV8GenOverflow(t *testing.T) {
+	now := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	g := NewGenWithOptions(
+		WithEpochFunc(func() time.Time { return now }),
+		WithMonotonicPolicy(MonotonicError),
+	)
+	tg, err := NewTimeV8Gen(g, TimeV8Config{Resolution: time.Millisecond, CounterBits: 4})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i <= 16; i++ {
+		_, err := tg.New()
+		if errors.Is(err, ErrClockSequenceOverflow) {
+			return
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	t.Fatal("generated 17 UUIDs with a 4-bit counter in the same tick without an error")
+}
+
+func testTimeV8GenRollback(t *testing.T) {
+	clock := &testClock{t: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)}
+	var events []MonotonicEvent
+	g := NewGenWithOptions(
+		WithEpochFunc(clock.Now),
+		WithMonotonicHook(func(ev MonotonicEvent) {
+			events = append(events, ev)
+		}),
+	)
+	tg, err := NewTimeV8Gen(g, TimeV8Config{Resolution: time.Microsecond, CounterBits: 12})
+	if err != nil {
+		t.Fatal(err)
+	}
+	u1, err := tg.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	clock.Set(clock.Now().Add(-time.Second))
+	u2, err := tg.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	u3, err := tg.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Compare(u1[:], u2[:]) >= 0 || bytes.Compare(u2[:], u3[:]) >= 0 {
+		t.Errorf("UUIDs %v, %v, %v are not increasing after the clock went back", u1, u2, u3)
+	}
+	if len(events) != 1 {
+		t.Fatalf("got %d events, want 1", len(events))
+	}
+	if ev := events[0]; !errors.Is(ev.Err, ErrClockRollback) || ev.Version != V8 || ev.Policy != MonotonicBorrow {
+		t.Errorf("unexpected event %+v", ev)
+	}
+}
+
+func testTimeV8GenTimeRange(t *testing.T) {
+	epoch := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	tg, err := NewTimeV8Gen(nil, TimeV8Config{Epoch: epoch, Resolution: time.Nanosecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 2^60 nanoseconds is about 36.5 years
+	for _, atTime := range []time.Time{epoch.Add(-time.Nanosecond), epoch.AddDate(37, 0, 0)} {
+		if _, err := tg.NewAtTime(atTime); !errors.Is(err, ErrTimeOutOfRange) {
+			t.Errorf("NewAtTime(%v) error = %v, want %v", atTime, err, ErrTimeOutOfRange)
+		}
+	}
+	if _, err := tg.NewAtTime(epoch.AddDate(36, 0, 0)); err != nil {
+		t.Errorf("NewAtTime() error = %v, want <nil>", err)
+	}
+}
+
+func testTimeV8GenInvalidConfig(t *testing.T) {
+	for _, cfg := range []TimeV8Config{
+		{Resolution: -time.Nanosecond},
+		{Resolution: 2 * time.Second},
+		{CounterBits: -1},
+		{CounterBits: 63},
+	} {
+		if _, err := NewTimeV8Gen(nil, cfg); !errors.Is(err, ErrInvalidTimeV8Config) {
+			t.Errorf("NewTimeV8Gen(%+v) error = %v, want %v", cfg, err, ErrInvalidTimeV8Config)
+		}
+	}
+}
+
+func testTimeV8GenFaultyRand(t *testing.T) {
+	g := NewGenWithOptions(WithRandomReader(&faultyReader{readToFail: 0}))
+	tg, err := NewTimeV8Gen(g, TimeV8Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	u, err := tg.New()
+	testErrCheck(t, "New()", "faulty", err)
+	if u != Nil {
+		t.Errorf("got %v on error, want Nil", u)
+	}
+}
@@ -24,14 +24,12 @@
 //
 // RFC-9562[1] provides the specification for versions 1, 3, 4, 5, 6, 7 and 8.
 //
-// DCE 1.1[2] provides the specification for version 2, but version 2 support
-// was removed from this package in v4 due to some concerns with the
-// specification itself. Reading the spec, it seems that it would result in
-// generating UUIDs that aren't very unique. In having read the spec it seemed
-// that our implementation did not meet the spec. It also seems to be at-odds
-// with RFC 9562, meaning we would need quite a bit of special code to support
-// it. Lastly, there were no Version 2 implementations that we could find to
-// ensure we were understanding the specification correctly.
+// DCE 1.1[2] provides the specification for version 2. Version 2 support was
+// removed from this package in v4 due to some concerns with the
+// specification itself: the local ID replaces most of the timestamp, which
+// results in generating UUIDs that aren't very unique. It is available again
+// for interoperability with systems that emit version 2 UUIDs, but should
+// not be used for new identifiers. See Gen.NewV2.
 //
 // [1] https://tools.ietf.org/html/rfc9562
 // [2] http://pubs.opengroup.org/onlinepubs/9696989899/chap5.htm#tagcjh_08_02_01_01
@@ -54,7 +52,7 @@ type UUID [Size]byte
 const (
 	_  byte = iota
 	V1      // Version 1 (date-time and MAC address)
-	_       // Version 2 (date-time and MAC address, DCE security version) [removed]
+	V2      // Version 2 (date-time and MAC address, DCE security version)
 	V3      // Version 3 (namespace name-based)
 	V4      // Version 4 (random)
 	V5      // Version 5 (namespace name-based)
@@ -117,6 +115,44 @@ func TimestampFromV1(u UUID) (Timestamp, error) {
 	return Timestamp(uint64(low) + (uint64(mid) << 32) + (uint64(hi) << 48)), nil
 }
 
+// TimestampFromV2 returns the Timestamp embedded within a V2 UUID.
+// Returns an error if the UUID is any version other than 2.
+//
+// The time_low field of a V2 UUID is replaced by the local ID, so only the
+// upper 28 bits of the timestamp are kept: the returned Timestamp is the
+// start of the window of 2^32 intervals (about 7 minutes) in which the UUID
+// was generated.
+func TimestampFromV2(u UUID) (Timestamp, error) {
+	if u.Version() != 2 {
+		err := fmt.Errorf("%w %s is version %d, not version 2", ErrInvalidVersion, u, u.Version())
+		return 0, err
+	}
+
+	mid := binary.BigEndian.Uint16(u[4:6])
+	hi := binary.BigEndian.Uint16(u[6:8]) & 0xfff
+
+	return Timestamp((uint64(mid) << 32) + (uint64(hi) << 48)), nil
+}
+
+// DomainFromV2 returns the DCE domain embedded within a V2 UUID, such as
+// DomainPerson. Returns an error if the UUID is any version other than 2.
+func DomainFromV2(u UUID) (byte, error) {
+	if u.Version() != 2 {
+		return 0, fmt.Errorf("%w %s is version %d, not version 2", ErrInvalidVersion, u, u.Version())
+	}
+	return u[9], nil
+}
+
+// IDFromV2 returns the local ID embedded within a V2 UUID, such as a POSIX
+// UID for DomainPerson. Returns an error if the UUID is any version other
+// than 2.
+func IDFromV2(u UUID) (uint32, error) {
+	if u.Version() != 2 {
+		return 0, fmt.Errorf("%w %s is version %d, not version 2", ErrInvalidVersion, u, u.Version())
+	}
+	return binary.BigEndian.Uint32(u[0:4]), nil
+}
+
 // TimestampFromV6 returns the Timestamp embedded within a V6 UUID. This
 // function returns an error if the UUID is any version other than 6.
 func TimestampFromV6(u UUID) (Timestamp, error) {
@@ -131,6 +167,67 @@ func TimestampFromV6(u UUID) (Timestamp, error) {
 	return Timestamp(uint64(low) + (uint64(mid) << 12) + (uint64(hi) << 28)), nil
 }
 
+// V1ToV6 returns the V6 UUID with the same timestamp, clock sequence and node
+// as the V1 UUID u, as described in RFC 9562 section 5.6. The conversion is
+// lossless: V6ToV1 returns u back. Returns an error if the UUID is any
+// version other than 1.
+func V1ToV6(u UUID) (UUID, error) {
+	ts, err := TimestampFromV1(u)
+	if err != nil {
+		return Nil, err
+	}
+
+	binary.BigEndian.PutUint32(u[0:], uint32(ts>>28))   // set time_high
+	binary.BigEndian.PutUint16(u[4:], uint16(ts>>12))   // set time_mid
+	binary.BigEndian.PutUint16(u[6:], uint16(ts&0xfff)) // set time_low (minus four version bits)
+	u.SetVersion(V6)
+
+	return u, nil
+}
+
+// V6ToV1 returns the V1 UUID with the same timestamp, clock sequence and node
+// as the V6 UUID u. It is the inverse of V1ToV6. Returns an error if the UUID
+// is any version other than 6.
+func V6ToV1(u UUID) (UUID, error) {
+	ts, err := TimestampFromV6(u)
+	if err != nil {
+		return Nil, err
+	}
+
+	binary.BigEndian.PutUint32(u[0:], uint32(ts))
+	binary.BigEndian.PutUint16(u[4:], uint16(ts>>32))
+	binary.BigEndian.PutUint16(u[6:], uint16(ts>>48))
+	u.SetVersion(V1)
+
+	return u, nil
+}
+
+// V1ToV6Slice converts every V1 UUID in us to V6 in place, with V1ToV6.
+// Returns an error if any UUID is a version other than 1, in which case us
+// is left unchanged.
+func V1ToV6Slice(us []UUID) error {
+	return convertSlice(us, V1, V1ToV6)
+}
+
+// V6ToV1Slice converts every V6 UUID in us to V1 in place, with V6ToV1.
+// Returns an error if any UUID is a version other than 6, in which case us
+// is left unchanged.
+func V6ToV1Slice(us []UUID) error {
+	return convertSlice(us, V6, V6ToV1)
+}
+
+func convertSlice(us []UUID, version byte, convert func(UUID) (UUID, error)) error {
+	for i, u := range us {
+		if u.Version() != version {
+			return fmt.Errorf("%w %s at index %d is version %d, not version %d", ErrInvalidVersion, u, i, u.Version(), version)
+		}
+	}
+	for i, u := range us {
+		us[i], _ = convert(u)
+	}
+	return nil
+}
+
 // TimestampFromV7 returns the Timestamp embedded within a V7 UUID. This
 // function returns an error if the UUID is any version other than 7.
 func TimestampFromV7(u UUID) (Timestamp, error) {
@@ -154,6 +251,25 @@ func TimestampFromV7(u UUID) (Timestamp, error) {
 	return Timestamp(tsNanos), nil
 }
 
+// TimestampFromV7SubMillisecond returns the Timestamp embedded within a V7
+// UUID generated with WithV7SubMillisecond, where the rand_a field holds the
+// fraction of the millisecond (RFC 9562 section 6.2, Method 3). The result
+// has a precision of 100ns instead of the 1ms of TimestampFromV7. This
+// function returns an error if the UUID is any version other than 7.
+//
+// For V7 UUIDs with random or counter data in rand_a, the result is off by
+// up to 1ms.
+func TimestampFromV7SubMillisecond(u UUID) (Timestamp, error) {
+	ts, err := TimestampFromV7(u)
+	if err != nil {
+		return 0, err
+	}
+
+	// rand_a holds the fraction of the millisecond in 1/4096 ms units.
+	frac := Timestamp(binary.BigEndian.Uint16(u[6:8]) & 0xfff)
+	return ts + frac*10000>>12, nil
+}
+
 // Nil is the nil UUID, as specified in RFC-9562, that has all 128 bits set to
 // zero.
 var Nil = UUID{}
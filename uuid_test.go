@@ -23,8 +23,10 @@ package uuid
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
 	"io"
+	"slices"
 	"testing"
 	"time"
 )
@@ -250,6 +252,62 @@ func TestTimestampFromV1(t *testing.T) {
 	}
 }
 
+func TestTimestampFromV2(t *testing.T) {
+	tests := []struct {
+		u       UUID
+		want    Timestamp
+		wanterr bool
+	}{
+		{u: Must(NewV4()), wanterr: true},
+		{u: Must(FromString("00000000-0000-2000-8000-000000000000")), want: 0},
+		// time_low of the V1 UUID c232ab00-9414-11ec-b3c8-9f6bdeced846 is lost
+		{u: Must(FromString("000003e8-9414-21ec-b300-9f6bdeced846")), want: 138648505420000000 &^ 0xffffffff},
+		{u: Must(FromString("ffffffff-ffff-2fff-ffff-ffffffffffff")), want: Timestamp(1<<60 - 1<<32)},
+	}
+	for _, tt := range tests {
+		got, goterr := TimestampFromV2(tt.u)
+		if tt.wanterr && goterr == nil {
+			t.Errorf("TimestampFromV2(%v) want error, got %v", tt.u, got)
+		} else if tt.want != got {
+			t.Errorf("TimestampFromV2(%v) got %v, want %v", tt.u, got, tt.want)
+		}
+	}
+}
+
+func TestFromV2(t *testing.T) {
+	tests := []struct {
+		u          UUID
+		wantDomain byte
+		wantID     uint32
+		wanterr    bool
+	}{
+		{u: Must(NewV4()), wanterr: true},
+		{u: Must(FromString("c232ab00-9414-11ec-b3c8-9f6bdeced846")), wanterr: true},
+		{u: Must(FromString("000003e8-9414-21ec-b300-9f6bdeced846")), wantDomain: DomainPerson, wantID: 1000},
+		{u: Must(FromString("00000064-9414-21ec-b301-9f6bdeced846")), wantDomain: DomainGroup, wantID: 100},
+		{u: Must(FromString("deadbeef-9414-21ec-b302-9f6bdeced846")), wantDomain: DomainOrg, wantID: 0xdeadbeef},
+	}
+	for _, tt := range tests {
+		domain, err := DomainFromV2(tt.u)
+		if tt.wanterr {
+			if err == nil {
+				t.Errorf("DomainFromV2(%v) want error, got %v", tt.u, domain)
+			}
+		} else if err != nil || domain != tt.wantDomain {
+			t.Errorf("DomainFromV2(%v) got %v, %v, want %v", tt.u, domain, err, tt.wantDomain)
+		}
+
+		id, err := IDFromV2(tt.u)
+		if tt.wanterr {
+			if err == nil {
+				t.Errorf("IDFromV2(%v) want error, got %v", tt.u, id)
+			}
+		} else if err != nil || id != tt.wantID {
+			t.Errorf("IDFromV2(%v) got %v, %v, want %v", tt.u, id, err, tt.wantID)
+		}
+	}
+}
+
 func TestTimestampFromV6(t *testing.T) {
 	tests := []struct {
 		u       UUID
@@ -275,6 +333,77 @@ func TestTimestampFromV6(t *testing.T) {
 	}
 }
 
+func TestV1ToV6(t *testing.T) {
+	tests := []struct {
+		v1 UUID
+		v6 UUID
+	}{
+		// RFC 9562 appendix A.1 and A.5
+		{v1: Must(FromString("C232AB00-9414-11EC-B3C8-9F6BDECED846")), v6: Must(FromString("1EC9414C-232A-6B00-B3C8-9F6BDECED846"))},
+		{v1: Must(FromString("00000000-0000-1000-8000-000000000000")), v6: Must(FromString("00000000-0000-6000-8000-000000000000"))},
+		{v1: Must(FromString("ffffffff-ffff-1fff-ffff-ffffffffffff")), v6: Must(FromString("ffffffff-ffff-6fff-ffff-ffffffffffff"))},
+		{v1: Must(FromString("424f137e-a2aa-11e8-98d0-529269fb1459")), v6: Must(FromString("1e8a2aa4-24f1-637e-98d0-529269fb1459"))},
+	}
+	for _, tt := range tests {
+		got, err := V1ToV6(tt.v1)
+		if err != nil || got != tt.v6 {
+			t.Errorf("V1ToV6(%v) = %v, %v, want %v", tt.v1, got, err, tt.v6)
+		}
+		got, err = V6ToV1(tt.v6)
+		if err != nil || got != tt.v1 {
+			t.Errorf("V6ToV1(%v) = %v, %v, want %v", tt.v6, got, err, tt.v1)
+		}
+	}
+
+	u := Must(NewV4())
+	if _, err := V1ToV6(u); !errors.Is(err, ErrInvalidVersion) {
+		t.Errorf("V1ToV6(%v) error = %v, want %v", u, err, ErrInvalidVersion)
+	}
+	if _, err := V6ToV1(u); !errors.Is(err, ErrInvalidVersion) {
+		t.Errorf("V6ToV1(%v) error = %v, want %v", u, err, ErrInvalidVersion)
+	}
+}
+
+func TestV1ToV6Slice(t *testing.T) {
+	g := NewGen()
+	v1 := make([]UUID, 100)
+	for i := range v1 {
+		v1[i] = Must(g.NewV1())
+	}
+
+	us := slices.Clone(v1)
+	if err := V1ToV6Slice(us); err != nil {
+		t.Fatal(err)
+	}
+	for i, u := range us {
+		if u.Version() != V6 {
+			t.Fatalf("UUID %d %v is version %d, want %d", i, u, u.Version(), V6)
+		}
+		if i > 0 && bytes.Compare(us[i-1][:8], u[:8]) > 0 {
+			t.Fatalf("UUID %d %v timestamp sorts before %v", i, u, us[i-1])
+		}
+	}
+	if err := V6ToV1Slice(us); err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(us, v1) {
+		t.Error("V6ToV1Slice did not restore the V1 UUIDs")
+	}
+
+	// mixed versions are rejected without converting any UUID
+	us = append(slices.Clone(v1), Must(NewV4()))
+	err := V1ToV6Slice(us)
+	if !errors.Is(err, ErrInvalidVersion) {
+		t.Errorf("V1ToV6Slice() error = %v, want %v", err, ErrInvalidVersion)
+	}
+	if !slices.Equal(us[:len(v1)], v1) {
+		t.Error("V1ToV6Slice changed the slice on error")
+	}
+	if err := V6ToV1Slice(nil); err != nil {
+		t.Errorf("V6ToV1Slice(nil) error = %v, want <nil>", err)
+	}
+}
+
 func TestTimestampFromV7(t *testing.T) {
 	tests := []struct {
 		u       UUID
@@ -303,6 +432,36 @@ func TestTimestampFromV7(t *testing.T) {
 	}
 }
 
+func TestTimestampFromV7SubMillisecond(t *testing.T) {
+	tests := []struct {
+		u    UUID
+		want Timestamp
+	}{
+		{u: Must(FromString("00000000-0000-7000-8000-000000000000")), want: 122192928000000000},
+		// 2022-02-22 19:22:22.0005 UTC, rand_a is 0x800 (half a millisecond)
+		{u: Must(FromString("017f22e2-79b0-7800-8000-000000000000")), want: 138648505420005000},
+		// the largest fraction rounds down to 100ns units
+		{u: Must(FromString("017f22e2-79b0-7fff-bfff-ffffffffffff")), want: 138648505420009997},
+	}
+	for _, tt := range tests {
+		got, err := TimestampFromV7SubMillisecond(tt.u)
+		if err != nil {
+			t.Errorf("TimestampFromV7SubMillisecond(%v) unexpected error: %v", tt.u, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("TimestampFromV7SubMillisecond(%v) got %v, want %v", tt.u, got, tt.want)
+		}
+		ms, err := TimestampFromV7(tt.u)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got-ms >= 10000 {
+			t.Errorf("TimestampFromV7SubMillisecond(%v) = %v, more than 1ms after %v", tt.u, got, ms)
+		}
+	}
+}
+
 func TestMinMaxTimestamps(t *testing.T) {
 	tests := []struct {
 		u    UUID
@@ -0,0 +1,328 @@
+// Copyright (C) 2013-2018 by Maxim Bublis <b@codemonkey.ru>
+//
+// Permission is hereby granted, free of charge, to any person obtaining
+// a copy of this software and associated documentation files (the
+// "Software"), to deal in the Software without restriction, including
+// without limitation the rights to use, copy, modify, merge, publish,
+// distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so, subject to
+// the following conditions:
+//
+// The above copyright notice and this permission notice shall be
+// included in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+// EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF
+// MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE
+// LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+// OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION
+// WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package uuid
+
+import (
+	"encoding/binary"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// v8Bits is the number of custom bits in a V8 UUID: all 128 bits but the 4
+// version bits and the 2 variant bits.
+const v8Bits = 122
+
+// V8Field is a named unsigned integer field of a V8Layout.
+type V8Field struct {
+	Name string
+	Bits int
+}
+
+// V8Layout describes how named fields are packed into the 122 custom bits of
+// a V8 UUID. Fields are packed in order, starting from the most significant
+// bit of the UUID, and skip the version and variant bits, so a field may
+// span them. Bits that are not covered by a field are zero.
+//
+// For example, the layout "shard:10, ts_ms:48, seq:14, rand:50" puts the
+// shard in the first 10 bits of the UUID. The 48-bit timestamp follows: its
+// first 38 bits end at the version bits, and its last 10 bits follow them.
+// The sequence spans the variant bits, and the random bits fill the rest.
+//
+// A field spanning the version or variant bits is not contiguous in the
+// bytes of the UUID. UUIDs still sort by their fields in order, as the
+// version and variant bits are the same in every UUID, but the field cannot
+// be read from, or compared as, a single range of bytes. Keep such fields,
+// like a timestamp meant for range queries on the UUID bytes, within the
+// first 48 bits.
+//
+// A V8Layout is safe for concurrent use.
+type V8Layout struct {
+	fields  []V8Field
+	offsets []int // offset of each field from the first custom bit
+	index   map[string]int
+}
+
+// NewV8Layout returns a V8Layout with the provided fields. Every field must
+// have a unique, non-empty name and between 1 and 64 bits, and the fields
+// must fit in 122 bits. Otherwise, an error wrapping ErrInvalidV8Layout is
+// returned.
+func NewV8Layout(fields ...V8Field) (*V8Layout, error) {
+	l := &V8Layout{
+		fields:  append([]V8Field(nil), fields...),
+		offsets: make([]int, len(fields)),
+		index:   make(map[string]int, len(fields)),
+	}
+
+	total := 0
+	for i, f := range fields {
+		if f.Name == "" {
+			return nil, fmt.Errorf("%w: field %d has no name", ErrInvalidV8Layout, i)
+		}
+		if _, ok := l.index[f.Name]; ok {
+			return nil, fmt.Errorf("%w: duplicate field %q", ErrInvalidV8Layout, f.Name)
+		}
+		if f.Bits < 1 || f.Bits > 64 {
+			return nil, fmt.Errorf("%w: field %q has %d bits, must be between 1 and 64", ErrInvalidV8Layout, f.Name, f.Bits)
+		}
+		l.index[f.Name] = i
+		l.offsets[i] = total
+		total += f.Bits
+	}
+	if total > v8Bits {
+		return nil, fmt.Errorf("%w: fields have %d bits, more than the %d available", ErrInvalidV8Layout, total, v8Bits)
+	}
+	return l, nil
+}
+
+// ParseV8Layout returns the V8Layout described by s, a comma-separated list
+// of fields written as name:bits, such as "shard:10, ts_ms:48, seq:14,
+// rand:50". See NewV8Layout.
+func ParseV8Layout(s string) (*V8Layout, error) {
+	var fields []V8Field
+	for _, spec := range strings.Split(s, ",") {
+		name, bits, ok := strings.Cut(strings.TrimSpace(spec), ":")
+		if !ok {
+			return nil, fmt.Errorf("%w: field %q is not name:bits", ErrInvalidV8Layout, strings.TrimSpace(spec))
+		}
+		n, err := strconv.Atoi(strings.TrimSpace(bits))
+		if err != nil {
+			return nil, fmt.Errorf("%w: field %q: %v", ErrInvalidV8Layout, strings.TrimSpace(spec), err)
+		}
+		fields = append(fields, V8Field{Name: strings.TrimSpace(name), Bits: n})
+	}
+	return NewV8Layout(fields...)
+}
+
+// Fields returns the fields of the layout, in order.
+func (l *V8Layout) Fields() []V8Field {
+	return append([]V8Field(nil), l.fields...)
+}
+
+// String returns the layout in the format accepted by ParseV8Layout.
+func (l *V8Layout) String() string {
+	var b strings.Builder
+	for i, f := range l.fields {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		fmt.Fprintf(&b, "%s:%d", f.Name, f.Bits)
+	}
+	return b.String()
+}
+
+// Encode returns the V8 UUID holding the provided field values. values is
+// either a map[string]uint64 keyed by field name, or a struct or pointer to
+// struct. The integer fields of a struct are matched to the fields of the
+// layout by their `uuid` tag, or by their name when they have no tag; other
+// struct fields, and fields tagged `uuid:"-"`, are ignored.
+//
+// Fields of the layout without a value are zero. An error wrapping
+// ErrV8FieldValue is returned when a value does not fit in its field, is
+// negative, or is for a field that is not in the layout.
+func (l *V8Layout) Encode(values any) (UUID, error) {
+	var acc uint128
+	set := func(name string, v uint64) error {
+		i, ok := l.index[name]
+		if !ok {
+			return fmt.Errorf("%w: no field %q in layout", ErrV8FieldValue, name)
+		}
+		f := l.fields[i]
+		if f.Bits < 64 && v>>f.Bits != 0 {
+			return fmt.Errorf("%w: %d does not fit in the %d bits of field %q", ErrV8FieldValue, v, f.Bits, name)
+		}
+		acc = acc.or(uint128{lo: v}.shl(uint(v8Bits - l.offsets[i] - f.Bits)))
+		return nil
+	}
+
+	if m, ok := values.(map[string]uint64); ok {
+		for name, v := range m {
+			if err := set(name, v); err != nil {
+				return Nil, err
+			}
+		}
+		return acc.v8(), nil
+	}
+
+	rv := reflect.ValueOf(values)
+	if rv.Kind() == reflect.Pointer && !rv.IsNil() {
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return Nil, fmt.Errorf("%w: cannot encode %T, want a map[string]uint64 or a struct", ErrV8FieldValue, values)
+	}
+	for name, fv := range l.structFields(rv, false) {
+		var v uint64
+		switch fv.Kind() {
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+			v = fv.Uint()
+		default:
+			n := fv.Int()
+			if n < 0 {
+				return Nil, fmt.Errorf("%w: negative value %d for field %q", ErrV8FieldValue, n, name)
+			}
+			v = uint64(n)
+		}
+		if err := set(name, v); err != nil {
+			return Nil, err
+		}
+	}
+	return acc.v8(), nil
+}
+
+// Decode returns the values of the fields of the layout in the V8 UUID u,
+// keyed by field name. Returns an error if the UUID is any version other
+// than 8.
+func (l *V8Layout) Decode(u UUID) (map[string]uint64, error) {
+	acc, err := v8Custom(u)
+	if err != nil {
+		return nil, err
+	}
+	values := make(map[string]uint64, len(l.fields))
+	for i, f := range l.fields {
+		values[f.Name] = l.field(acc, i)
+	}
+	return values, nil
+}
+
+// DecodeInto stores the values of the fields of the layout in the V8 UUID u
+// into the struct pointed to by v, whose fields are matched as for Encode.
+// Returns an error if the UUID is any version other than 8, or wrapping
+// ErrV8FieldValue if a value does not fit in its struct field.
+func (l *V8Layout) DecodeInto(u UUID, v any) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("%w: cannot decode into %T, want a pointer to a struct", ErrV8FieldValue, v)
+	}
+	acc, err := v8Custom(u)
+	if err != nil {
+		return err
+	}
+	for name, fv := range l.structFields(rv.Elem(), true) {
+		x := l.field(acc, l.index[name])
+		switch fv.Kind() {
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+			if fv.OverflowUint(x) {
+				return fmt.Errorf("%w: %d overflows %s field %q", ErrV8FieldValue, x, fv.Type(), name)
+			}
+			fv.SetUint(x)
+		default:
+			if x > 1<<63-1 || fv.OverflowInt(int64(x)) {
+				return fmt.Errorf("%w: %d overflows %s field %q", ErrV8FieldValue, x, fv.Type(), name)
+			}
+			fv.SetInt(int64(x))
+		}
+	}
+	return nil
+}
+
+// structFields returns the integer fields of the struct rv, keyed by the name
+// of the layout field they match. When known is true, struct fields that do
+// not match a field of the layout are left out.
+func (l *V8Layout) structFields(rv reflect.Value, known bool) map[string]reflect.Value {
+	fields := make(map[string]reflect.Value)
+	t := rv.Type()
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if !sf.IsExported() {
+			continue
+		}
+		switch sf.Type.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+			reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		default:
+			continue
+		}
+		name, tagged := sf.Tag.Lookup("uuid")
+		if name == "-" {
+			continue
+		}
+		if !tagged {
+			name = sf.Name
+		}
+		if _, ok := l.index[name]; !ok && (known || !tagged) {
+			continue
+		}
+		fields[name] = rv.Field(i)
+	}
+	return fields
+}
+
+// field returns the value of the field i of the layout in acc.
+func (l *V8Layout) field(acc uint128, i int) uint64 {
+	f := l.fields[i]
+	v := acc.shr(uint(v8Bits - l.offsets[i] - f.Bits)).lo
+	if f.Bits < 64 {
+		v &= 1<<f.Bits - 1
+	}
+	return v
+}
+
+// v8Custom returns the 122 custom bits of the V8 UUID u.
+func v8Custom(u UUID) (uint128, error) {
+	if u.Version() != V8 {
+		return uint128{}, fmt.Errorf("%w %s is version %d, not version 8", ErrInvalidVersion, u, u.Version())
+	}
+	hi := binary.BigEndian.Uint64(u[0:8])
+	customA := hi >> 16
+	customB := hi & 0xfff
+	customC := binary.BigEndian.Uint64(u[8:16]) & (1<<62 - 1)
+	return uint128{lo: customA}.shl(74).or(uint128{lo: customB}.shl(62)).or(uint128{lo: customC}), nil
+}
+
+// uint128 is an unsigned 128-bit integer.
+type uint128 struct {
+	hi, lo uint64
+}
+
+func (x uint128) or(y uint128) uint128 {
+	return uint128{x.hi | y.hi, x.lo | y.lo}
+}
+
+func (x uint128) shl(n uint) uint128 {
+	if n >= 64 {
+		return uint128{x.lo << (n - 64), 0}
+	}
+	return uint128{x.hi<<n | x.lo>>(64-n), x.lo << n}
+}
+
+func (x uint128) shr(n uint) uint128 {
+	if n >= 64 {
+		return uint128{0, x.hi >> (n - 64)}
+	}
+	return uint128{x.hi >> n, x.lo>>n | x.hi<<(64-n)}
+}
+
+// v8 returns the V8 UUID holding the 122 custom bits of x: custom_a,
+// custom_b and custom_c, as described in RFC 9562 section 5.8.
+func (x uint128) v8() UUID {
+	var u UUID
+	customA := x.shr(74).lo
+	customB := x.shr(62).lo & 0xfff
+	customC := x.lo & (1<<62 - 1)
+	binary.BigEndian.PutUint64(u[0:8], customA<<16|customB)
+	binary.BigEndian.PutUint64(u[8:16], customC)
+	u.SetVersion(V8)
+	u.SetVariant(VariantRFC9562)
+	return u
+}
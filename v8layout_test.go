@@ -0,0 +1,233 @@
+// Copyright (C) 2013-2018 by Maxim Bublis <b@codemonkey.ru>
+//
+// Permission is hereby granted, free of charge, to any person obtaining
+// a copy of this software and associated documentation files (the
+// "Software"), to deal in the Software without restriction, including
+// without limitation the rights to use, copy, modify, merge, publish,
+// distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so, subject to
+// the following conditions:
+//
+// The above copyright notice and this permission notice shall be
+// included in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+// EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF
+// MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE
+// LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+// OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION
+// WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package uuid
+
+import (
+	"errors"
+	"maps"
+	"testing"
+)
+
+func TestV8Layout(t *testing.T) {
+	t.Run("Parse", testV8LayoutParse)
+	t.Run("Invalid", testV8LayoutInvalid)
+	t.Run("NewV8Compatible", testV8LayoutNewV8Compatible)
+	t.Run("RoundTrip", testV8LayoutRoundTrip)
+	t.Run("Struct", testV8LayoutStruct)
+	t.Run("InvalidValues", testV8LayoutInvalidValues)
+}
+
+func testV8LayoutParse(t *testing.T) {
+	l, err := ParseV8Layout(" shard:10,ts_ms : 48, seq:14, rand:50")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := l.String(), "shard:10, ts_ms:48, seq:14, rand:50"; got != want {
+		t.Errorf("String() = %q, want %q", got, want)
+	}
+	fields := l.Fields()
+	if len(fields) != 4 || fields[1] != (V8Field{Name: "ts_ms", Bits: 48}) {
+		t.Errorf("Fields() = %v", fields)
+	}
+}
+
+func testV8LayoutInvalid(t *testing.T) {
+	for _, s := range []string{
+		"",
+		"a",
+		"a:x",
+		":10",
+		"a:0",
+		"a:65",
+		"a:10, a:10",
+		"a:64, b:58, c:1",
+	} {
+		if _, err := ParseV8Layout(s); !errors.Is(err, ErrInvalidV8Layout) {
+			t.Errorf("ParseV8Layout(%q) error = %v, want %v", s, err, ErrInvalidV8Layout)
+		}
+	}
+}
+
+func testV8LayoutNewV8Compatible(t *testing.T) {
+	// the three fields of NewV8 are a valid layout
+	l, err := ParseV8Layout("a:48, b:12, c:62")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, err := NewV8(
+		[]byte{0x32, 0x0c, 0x3d, 0x4d, 0xcc, 0x00},
+		[]byte{0x07, 0x5b},
+		[]byte{0x0e, 0xc3, 0x4f, 0x2e, 0x8a, 0x9e, 0x3f, 0x65},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := l.Encode(map[string]uint64{
+		"a": 0x320c3d4dcc00,
+		"b": 0x75b,
+		"c": 0x0ec34f2e8a9e3f65,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != want {
+		t.Errorf("Encode() = %v, want %v", got, want)
+	}
+	if got, want := got.String(), "320c3d4d-cc00-875b-8ec3-4f2e8a9e3f65"; got != want {
+		t.Errorf("Encode() = %s, want %s", got, want)
+	}
+}
+
+func testV8LayoutRoundTrip(t *testing.T) {
+	l, err := ParseV8Layout("shard:10, ts_ms:48, seq:14, rand:50")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, values := range []map[string]uint64{
+		{"shard": 0, "ts_ms": 0, "seq": 0, "rand": 0},
+		{"shard": 1<<10 - 1, "ts_ms": 1<<48 - 1, "seq": 1<<14 - 1, "rand": 1<<50 - 1},
+		{"shard": 0x2a5, "ts_ms": 1645557742000, "seq": 0x2001, "rand": 0x2aaaaaaaaaaaa},
+	} {
+		u, err := l.Encode(values)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, want := u.Version(), V8; got != want {
+			t.Errorf("%v: version = %d, want %d", u, got, want)
+		}
+		if got, want := u.Variant(), VariantRFC9562; got != want {
+			t.Errorf("%v: variant = %d, want %d", u, got, want)
+		}
+		got, err := l.Decode(u)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !maps.Equal(got, values) {
+			t.Errorf("Decode(%v) = %v, want %v", u, got, values)
+		}
+	}
+
+	// missing fields are zero, and unused bits stay zero
+	l, err = ParseV8Layout("x:3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	u, err := l.Encode(map[string]uint64{"x": 5})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := u.String(), "a0000000-0000-8000-8000-000000000000"; got != want {
+		t.Errorf("Encode() = %s, want %s", got, want)
+	}
+}
+
+func testV8LayoutStruct(t *testing.T) {
+	type key struct {
+		Shard   uint16 `uuid:"shard"`
+		TsMs    int64  `uuid:"ts_ms"`
+		Seq     uint32 `uuid:"seq"`
+		Comment string
+		Ignored uint64 `uuid:"-"`
+		rand    uint64
+	}
+	l, err := ParseV8Layout("shard:10, ts_ms:48, seq:14")
+	if err != nil {
+		t.Fatal(err)
+	}
+	in := key{Shard: 42, TsMs: 1645557742000, Seq: 7, Comment: "x", Ignored: 1, rand: 1}
+	u, err := l.Encode(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u2, err := l.Encode(&in); err != nil || u2 != u {
+		t.Errorf("Encode(&in) = %v, %v, want %v", u2, err, u)
+	}
+
+	var out key
+	if err := l.DecodeInto(u, &out); err != nil {
+		t.Fatal(err)
+	}
+	if want := (key{Shard: 42, TsMs: 1645557742000, Seq: 7}); out != want {
+		t.Errorf("DecodeInto() = %+v, want %+v", out, want)
+	}
+
+	// untagged fields are matched by name
+	type named struct {
+		A uint8
+		B uint8
+	}
+	l, err = ParseV8Layout("A:4, B:4")
+	if err != nil {
+		t.Fatal(err)
+	}
+	u, err = l.Encode(named{A: 0xc, B: 0x3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u[0] != 0xc3 {
+		t.Errorf("Encode() = %v, want c3 as the first byte", u)
+	}
+}
+
+func testV8LayoutInvalidValues(t *testing.T) {
+	l, err := ParseV8Layout("a:4, b:64")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name   string
+		values any
+	}{
+		{"Overflow", map[string]uint64{"a": 16}},
+		{"UnknownField", map[string]uint64{"c": 1}},
+		{"Negative", struct {
+			A int `uuid:"a"`
+		}{-1}},
+		{"UnknownTag", struct {
+			X uint8 `uuid:"x"`
+		}{}},
+		{"NotAStruct", 42},
+		{"NilPointer", (*struct{ A int })(nil)},
+	}
+	for _, tt := range tests {
+		if _, err := l.Encode(tt.values); !errors.Is(err, ErrV8FieldValue) {
+			t.Errorf("%s: Encode() error = %v, want %v", tt.name, err, ErrV8FieldValue)
+		}
+	}
+
+	u, err := l.Encode(map[string]uint64{"b": 1 << 63})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var small struct {
+		B int64 `uuid:"b"`
+	}
+	if err := l.DecodeInto(u, &small); !errors.Is(err, ErrV8FieldValue) {
+		t.Errorf("DecodeInto() error = %v, want %v", err, ErrV8FieldValue)
+	}
+	if err := l.DecodeInto(u, small); !errors.Is(err, ErrV8FieldValue) {
+		t.Errorf("DecodeInto(struct) error = %v, want %v", err, ErrV8FieldValue)
+	}
+	if _, err := l.Decode(Must(NewV4())); !errors.Is(err, ErrInvalidVersion) {
+		t.Errorf("Decode(V4) error = %v, want %v", err, ErrInvalidVersion)
+	}
+}
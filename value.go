@@ -0,0 +1,376 @@
+// Copyright (C) 2013-2018 by Maxim Bublis <b@codemonkey.ru>
+//
+// Permission is hereby granted, free of charge, to any person obtaining
+// a copy of this software and associated documentation files (the
+// "Software"), to deal in the Software without restriction, including
+// without limitation the rights to use, copy, modify, merge, publish,
+// distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so, subject to
+// the following conditions:
+//
+// The above copyright notice and this permission notice shall be
+// included in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+// EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF
+// MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE
+// LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+// OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION
+// WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package uuid
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"reflect"
+	"slices"
+	"strings"
+	"time"
+)
+
+// FromValue returns a V5 UUID for the Go value v under the namespace UUID
+// ns. v is hashed in a canonical encoding, so equal values produce the same
+// UUID across processes and releases, regardless of map ordering or float
+// formatting. See FromValueV8 for the encoding.
+func FromValue(ns UUID, v any) (UUID, error) {
+	b, err := canonicalValue(v)
+	if err != nil {
+		return Nil, err
+	}
+	return NewV5Bytes(ns, b), nil
+}
+
+// FromValueV8 is like FromValue, but returns a V8 UUID from the SHA-256
+// hash of the canonical encoding of v, like NewV8Hash.
+//
+// The canonical encoding of a value depends on its kind:
+//
+//   - Signed integers are encoded as an int64, unsigned integers as a
+//     uint64, and floats as a float64, so the size of a type does not
+//     change the encoding. Negative zero is encoded as zero, and all NaNs
+//     are encoded as the same NaN.
+//   - Strings, byte slices and byte arrays are encoded as their bytes.
+//   - Slices and arrays are encoded as their elements, in order.
+//   - Maps are encoded as their entries, sorted by the encoding of their
+//     keys.
+//   - Structs are encoded as their fields, sorted by name. A field is named
+//     by its `uuid` tag, or by its Go name when it has no tag, and fields
+//     tagged `uuid:"-"` are left out. Embedded structs are fields named after
+//     their type. Unexported fields cannot be encoded, so they must be
+//     tagged `uuid:"-"` unless the struct is a marshaler.
+//   - Pointers and interfaces are encoded as the value they hold, and nil
+//     values as nil.
+//   - A time.Time is encoded as its instant, regardless of its location.
+//     Other types implementing encoding.BinaryMarshaler, with a value or a
+//     pointer receiver, are encoded as the output of MarshalBinary.
+//
+// Each value is prefixed with its kind, and variable-length values with
+// their length, so distinct values have distinct encodings. Returns an error
+// wrapping ErrTypeConvertError if v holds channels, functions, cyclic
+// pointers or structs with untagged unexported fields, such as *big.Int, or
+// if MarshalBinary fails.
+func FromValueV8(ns UUID, v any) (UUID, error) {
+	b, err := canonicalValue(v)
+	if err != nil {
+		return Nil, err
+	}
+	return NewV8Hash(nil, ns, b)
+}
+
+// The kinds prefixing the values in the canonical encoding.
+const (
+	valueNil     = 'n'
+	valueBool    = 'b'
+	valueInt     = 'i'
+	valueUint    = 'u'
+	valueFloat   = 'f'
+	valueComplex = 'c'
+	valueString  = 's'
+	valueBytes   = 'x'
+	valueList    = 'l'
+	valueMap     = 'm'
+	valueStruct  = 'o'
+	valueTime    = 't'
+	valueBinary  = 'B'
+)
+
+var (
+	timeType            = reflect.TypeFor[time.Time]()
+	binaryMarshalerType = reflect.TypeFor[encoding.BinaryMarshaler]()
+)
+
+// valueEncoder writes the canonical encoding of values.
+type valueEncoder struct {
+	buf []byte
+	// seen holds the pointers, maps and slices being encoded, to detect
+	// cycles.
+	seen map[seenValue]bool
+}
+
+// seenValue identifies a pointer, map or slice being encoded.
+type seenValue struct {
+	ptr uintptr
+	typ reflect.Type
+	len int
+}
+
+func newSeenValue(rv reflect.Value) seenValue {
+	key := seenValue{ptr: rv.Pointer(), typ: rv.Type()}
+	if rv.Kind() == reflect.Slice {
+		key.len = rv.Len()
+	}
+	return key
+}
+
+// canonicalValue returns the canonical encoding of v.
+func canonicalValue(v any) ([]byte, error) {
+	e := &valueEncoder{}
+	if err := e.encode(reflect.ValueOf(v)); err != nil {
+		return nil, err
+	}
+	return e.buf, nil
+}
+
+func (e *valueEncoder) encode(rv reflect.Value) error {
+	if !rv.IsValid() {
+		e.buf = append(e.buf, valueNil)
+		return nil
+	}
+
+	t := rv.Type()
+	if t == timeType {
+		tm := rv.Interface().(time.Time)
+		e.buf = append(e.buf, valueTime)
+		e.buf = binary.BigEndian.AppendUint64(e.buf, uint64(tm.Unix()))
+		e.buf = binary.BigEndian.AppendUint32(e.buf, uint32(tm.Nanosecond()))
+		return nil
+	}
+	if m, ok := binaryMarshaler(rv); ok {
+		b, err := m.MarshalBinary()
+		if err != nil {
+			return fmt.Errorf("%w %s to UUID: %w", ErrTypeConvertError, t, err)
+		}
+		e.buf = append(e.buf, valueBinary)
+		e.appendBytes(b)
+		return nil
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		e.buf = append(e.buf, valueBool)
+		if rv.Bool() {
+			e.buf = append(e.buf, 1)
+		} else {
+			e.buf = append(e.buf, 0)
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		e.buf = append(e.buf, valueInt)
+		e.buf = binary.BigEndian.AppendUint64(e.buf, uint64(rv.Int()))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		e.buf = append(e.buf, valueUint)
+		e.buf = binary.BigEndian.AppendUint64(e.buf, rv.Uint())
+	case reflect.Float32, reflect.Float64:
+		e.buf = append(e.buf, valueFloat)
+		e.appendFloat(rv.Float())
+	case reflect.Complex64, reflect.Complex128:
+		c := rv.Complex()
+		e.buf = append(e.buf, valueComplex)
+		e.appendFloat(real(c))
+		e.appendFloat(imag(c))
+	case reflect.String:
+		e.buf = append(e.buf, valueString)
+		e.appendBytes([]byte(rv.String()))
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			e.buf = append(e.buf, valueBytes)
+			if t.Kind() == reflect.Slice {
+				e.appendBytes(rv.Bytes())
+			} else {
+				b := make([]byte, rv.Len())
+				reflect.Copy(reflect.ValueOf(b), rv)
+				e.appendBytes(b)
+			}
+			return nil
+		}
+		if t.Kind() == reflect.Slice {
+			if rv.IsNil() {
+				e.buf = append(e.buf, valueNil)
+				return nil
+			}
+			if err := e.enter(rv); err != nil {
+				return err
+			}
+			defer e.leave(rv)
+		}
+		e.buf = append(e.buf, valueList)
+		e.buf = binary.AppendUvarint(e.buf, uint64(rv.Len()))
+		for i := 0; i < rv.Len(); i++ {
+			if err := e.encode(rv.Index(i)); err != nil {
+				return err
+			}
+		}
+	case reflect.Map:
+		if rv.IsNil() {
+			e.buf = append(e.buf, valueNil)
+			return nil
+		}
+		if err := e.enter(rv); err != nil {
+			return err
+		}
+		defer e.leave(rv)
+		return e.encodeMap(rv)
+	case reflect.Struct:
+		return e.encodeStruct(rv)
+	case reflect.Pointer, reflect.Interface:
+		if rv.IsNil() {
+			e.buf = append(e.buf, valueNil)
+			return nil
+		}
+		if t.Kind() == reflect.Pointer {
+			if err := e.enter(rv); err != nil {
+				return err
+			}
+			defer e.leave(rv)
+		}
+		return e.encode(rv.Elem())
+	default:
+		return fmt.Errorf("%w %s to UUID", ErrTypeConvertError, t)
+	}
+	return nil
+}
+
+// binaryMarshaler returns rv as an encoding.BinaryMarshaler, when its type or
+// a pointer to its type implements it. Pointers and interfaces are encoded as
+// the value they hold, so they are never marshalers themselves.
+func binaryMarshaler(rv reflect.Value) (encoding.BinaryMarshaler, bool) {
+	t := rv.Type()
+	switch {
+	case t.Kind() == reflect.Pointer || t.Kind() == reflect.Interface:
+		return nil, false
+	case t.Implements(binaryMarshalerType):
+		return rv.Interface().(encoding.BinaryMarshaler), true
+	case reflect.PointerTo(t).Implements(binaryMarshalerType):
+		if !rv.CanAddr() {
+			// copy rv to call MarshalBinary on a pointer to it
+			p := reflect.New(t)
+			p.Elem().Set(rv)
+			rv = p.Elem()
+		}
+		return rv.Addr().Interface().(encoding.BinaryMarshaler), true
+	}
+	return nil, false
+}
+
+// encodeMap appends the entries of the map rv, sorted by the encoding of
+// their keys.
+func (e *valueEncoder) encodeMap(rv reflect.Value) error {
+	type entry struct{ key, value []byte }
+	entries := make([]entry, 0, rv.Len())
+	start := len(e.buf)
+	iter := rv.MapRange()
+	for iter.Next() {
+		if err := e.encode(iter.Key()); err != nil {
+			return err
+		}
+		k := len(e.buf)
+		if err := e.encode(iter.Value()); err != nil {
+			return err
+		}
+		entries = append(entries, entry{
+			key:   bytes.Clone(e.buf[start:k]),
+			value: bytes.Clone(e.buf[k:]),
+		})
+		e.buf = e.buf[:start]
+	}
+	slices.SortFunc(entries, func(a, b entry) int {
+		return bytes.Compare(a.key, b.key)
+	})
+
+	e.buf = append(e.buf, valueMap)
+	e.buf = binary.AppendUvarint(e.buf, uint64(len(entries)))
+	for _, en := range entries {
+		e.buf = append(e.buf, en.key...)
+		e.buf = append(e.buf, en.value...)
+	}
+	return nil
+}
+
+// encodeStruct appends the exported fields of the struct rv, sorted by name.
+// Unexported fields must be tagged `uuid:"-"`, as they cannot be encoded.
+func (e *valueEncoder) encodeStruct(rv reflect.Value) error {
+	type field struct {
+		name  string
+		value reflect.Value
+	}
+	var fields []field
+	t := rv.Type()
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		name, tagged := sf.Tag.Lookup("uuid")
+		if name == "-" {
+			continue
+		}
+		if !sf.IsExported() {
+			return fmt.Errorf("%w %s to UUID: unexported field %s", ErrTypeConvertError, t, sf.Name)
+		}
+		if !tagged {
+			name = sf.Name
+		}
+		fields = append(fields, field{name: name, value: rv.Field(i)})
+	}
+	slices.SortStableFunc(fields, func(a, b field) int {
+		return strings.Compare(a.name, b.name)
+	})
+
+	e.buf = append(e.buf, valueStruct)
+	e.buf = binary.AppendUvarint(e.buf, uint64(len(fields)))
+	for _, f := range fields {
+		e.appendBytes([]byte(f.name))
+		if err := e.encode(f.value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// appendBytes appends the length of b, followed by b.
+func (e *valueEncoder) appendBytes(b []byte) {
+	e.buf = binary.AppendUvarint(e.buf, uint64(len(b)))
+	e.buf = append(e.buf, b...)
+}
+
+// appendFloat appends f, with negative zero as zero and all NaNs as the
+// same NaN.
+func (e *valueEncoder) appendFloat(f float64) {
+	switch {
+	case f == 0:
+		f = 0
+	case math.IsNaN(f):
+		f = math.NaN()
+	}
+	e.buf = binary.BigEndian.AppendUint64(e.buf, math.Float64bits(f))
+}
+
+// enter records that the pointer, map or slice rv is being encoded, and
+// returns an error if it already is, as the value is then cyclic.
+func (e *valueEncoder) enter(rv reflect.Value) error {
+	key := newSeenValue(rv)
+	if e.seen[key] {
+		return fmt.Errorf("%w cyclic %s to UUID", ErrTypeConvertError, rv.Type())
+	}
+	if e.seen == nil {
+		e.seen = make(map[seenValue]bool)
+	}
+	e.seen[key] = true
+	return nil
+}
+
+// leave records that the pointer, map or slice rv is encoded.
+func (e *valueEncoder) leave(rv reflect.Value) {
+	delete(e.seen, newSeenValue(rv))
+}
@@ -0,0 +1,178 @@
+// Copyright (C) 2013-2018 by Maxim Bublis <b@codemonkey.ru>
+//
+// Permission is hereby granted, free of charge, to any person obtaining
+// a copy of this software and associated documentation files (the
+// "Software"), to deal in the Software without restriction, including
+// without limitation the rights to use, copy, modify, merge, publish,
+// distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so, subject to
+// the following conditions:
+//
+// The above copyright notice and this permission notice shall be
+// included in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+// EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF
+// MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE
+// LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+// OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION
+// WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package uuid
+
+import (
+	"bytes"
+	"errors"
+	"math"
+	"math/big"
+	"testing"
+	"time"
+)
+
+func TestCanonicalValue(t *testing.T) {
+	got, err := canonicalValue(map[string]int{"b": 2, "a": 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []byte{
+		'm', 2,
+		's', 1, 'a', 'i', 0, 0, 0, 0, 0, 0, 0, 1,
+		's', 1, 'b', 'i', 0, 0, 0, 0, 0, 0, 0, 2,
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("canonicalValue() = %q, want %q", got, want)
+	}
+}
+
+// counter implements encoding.BinaryMarshaler with a pointer receiver, and
+// has no exported fields.
+type counter struct{ n int }
+
+func (c *counter) MarshalBinary() ([]byte, error) {
+	return []byte{byte(c.n)}, nil
+}
+
+func TestFromValue(t *testing.T) {
+	type record struct {
+		ID      int64
+		Name    string `uuid:"name"`
+		Tags    map[string]float64
+		Updated time.Time `uuid:"-"`
+		secret  string    `uuid:"-"`
+	}
+	type reordered struct {
+		Name    string `uuid:"name"`
+		Tags    map[string]float64
+		ID      int32
+		Comment string `uuid:"-"`
+	}
+	at := time.Date(2024, 1, 2, 3, 4, 5, 6, time.UTC)
+
+	equal := []struct {
+		name string
+		a, b any
+	}{
+		{"MapOrder", map[string]int{"a": 1, "b": 2, "c": 3}, map[string]int{"c": 3, "b": 2, "a": 1}},
+		{"IntSizes", int8(5), int64(5)},
+		{"UintSizes", uint16(5), uint(5)},
+		{"FloatSizes", float32(1.5), 1.5},
+		{"NegativeZero", math.Copysign(0, -1), 0.0},
+		{"NaN", math.NaN(), math.Float64frombits(0x7ff8000000000001)},
+		{"Pointer", &record{ID: 1}, record{ID: 1}},
+		{"Interface", []any{"a", 1}, []any{"a", 1}},
+		{"ByteArray", [3]byte{1, 2, 3}, []byte{1, 2, 3}},
+		{"PointerMarshaler", &counter{n: 5}, counter{n: 5}},
+		{"TimeLocation", at, at.In(time.FixedZone("UTC+1", 3600))},
+		{
+			"StructFields",
+			record{ID: 7, Name: "x", Tags: map[string]float64{"k": 1}, Updated: at, secret: "s"},
+			reordered{ID: 7, Name: "x", Tags: map[string]float64{"k": 1}, Comment: "c"},
+		},
+	}
+	for _, tt := range equal {
+		t.Run(tt.name, func(t *testing.T) {
+			a, err := FromValue(NamespaceOID, tt.a)
+			if err != nil {
+				t.Fatal(err)
+			}
+			b, err := FromValue(NamespaceOID, tt.b)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if a != b {
+				t.Errorf("FromValue(%v) = %s, FromValue(%v) = %s", tt.a, a, tt.b, b)
+			}
+		})
+	}
+
+	different := []struct {
+		name string
+		a, b any
+	}{
+		{"Signedness", 5, uint(5)},
+		{"Strings", []string{"ab"}, []string{"a", "b"}},
+		{"NilSlice", []int(nil), []int{}},
+		{"IntFloat", 1, 1.0},
+		{"FieldNames", struct{ A, B int }{1, 2}, struct{ A, C int }{1, 2}},
+		{"MapValues", map[int]string{1: "a", 2: "b"}, map[int]string{1: "b", 2: "a"}},
+		{"Times", at, at.Add(time.Nanosecond)},
+		{"PointerMarshalers", counter{n: 5}, counter{n: 6}},
+		{"MapPointerMarshalers", map[string]counter{"a": {n: 5}}, map[string]counter{"a": {n: 6}}},
+	}
+	for _, tt := range different {
+		t.Run(tt.name, func(t *testing.T) {
+			a, _ := FromValue(NamespaceOID, tt.a)
+			b, _ := FromValue(NamespaceOID, tt.b)
+			if a == b {
+				t.Errorf("FromValue(%v) == FromValue(%v) (%s)", tt.a, tt.b, a)
+			}
+		})
+	}
+
+	t.Run("Version", func(t *testing.T) {
+		u, err := FromValue(NamespaceOID, "x")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, want := u.Version(), V5; got != want {
+			t.Errorf("FromValue() version = %d, want %d", got, want)
+		}
+		u, err = FromValueV8(NamespaceOID, "x")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, want := u.Version(), V8; got != want {
+			t.Errorf("FromValueV8() version = %d, want %d", got, want)
+		}
+		if u2, _ := FromValueV8(NamespaceDNS, "x"); u2 == u {
+			t.Errorf("FromValueV8() = %s under different namespaces", u)
+		}
+	})
+
+	t.Run("Errors", func(t *testing.T) {
+		type node struct{ Next *node }
+		cyclic := &node{}
+		cyclic.Next = cyclic
+		m := map[string]any{}
+		m["self"] = m
+		s := []any{nil}
+		s[0] = s
+
+		unexported := []any{big.NewInt(5), struct{ n int }{5}, struct{ A, b int }{1, 2}}
+		for _, v := range append([]any{make(chan int), func() {}, cyclic, m, s, []any{1, func() {}}}, unexported...) {
+			if u, err := FromValue(NamespaceOID, v); !errors.Is(err, ErrTypeConvertError) {
+				t.Errorf("FromValue(%T) = %s, %v, want %v", v, u, err, ErrTypeConvertError)
+			}
+			if u, err := FromValueV8(NamespaceOID, v); !errors.Is(err, ErrTypeConvertError) {
+				t.Errorf("FromValueV8(%T) = %s, %v, want %v", v, u, err, ErrTypeConvertError)
+			}
+		}
+
+		// shared, acyclic pointers are not cycles
+		shared := &node{}
+		if _, err := FromValue(NamespaceOID, []*node{shared, shared}); err != nil {
+			t.Errorf("FromValue() error = %v", err)
+		}
+	})
+}